@@ -0,0 +1,269 @@
+package entrolytics
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultVitalAggregationWindow is the default period over which vitals are aggregated.
+const DefaultVitalAggregationWindow = time.Minute
+
+// DefaultVitalMaxPaths is the default number of distinct paths not matching
+// a route that are summarized separately in each window.
+const DefaultVitalMaxPaths = 100
+
+// OtherVitalPath is the path reported for samples whose paths are not
+// summarized separately because MaxPaths was reached.
+const OtherVitalPath = "other"
+
+// VitalAggregatorOptions configures a VitalAggregator.
+type VitalAggregatorOptions struct {
+	// Window is the aggregation period. Summaries are sent at the end of
+	// each window. Defaults to DefaultVitalAggregationWindow.
+	Window time.Duration
+
+	// RelativeAccuracy of the percentile sketches.
+	// Defaults to DefaultSketchRelativeAccuracy.
+	RelativeAccuracy float64
+
+	// RawSampleRate is the fraction of samples that are also sent
+	// individually with TrackVitalWithContext, between 0 and 1.
+	// Defaults to 0, which sends summaries only.
+	RawSampleRate float64
+
+	// Routes lists the page routes as http.ServeMux patterns, such as
+	// "/products/{slug}". Samples are summarized per matching route
+	// template rather than per URL, which keeps the number of summaries
+	// bounded. As with ServeMux, "/" matches every path; use "/{$}" for the
+	// home page alone. NewVitalAggregator panics if a pattern is invalid.
+	Routes []string
+
+	// MaxPaths is the number of distinct paths not matching Routes that are
+	// summarized separately in each window, after normalizing IDs with
+	// DefaultPathRules. Samples for further paths are summarized under
+	// OtherVitalPath. Defaults to DefaultVitalMaxPaths.
+	MaxPaths int
+
+	// OnError is called when an error occurs during background sending.
+	OnError func(err error)
+}
+
+// VitalAggregator buckets Web Vital samples per website, route and metric
+// over a window and sends p50/p75/p95 summaries instead of every sample.
+// Summaries are sent to "/api/collect/vitals/summary", which
+// RateLimiterOptions.Endpoints can limit. It is safe for concurrent use.
+//
+// Example:
+//
+//	agg := entrolytics.NewVitalAggregator(client, entrolytics.VitalAggregatorOptions{
+//	    Routes:        []string{"/products/{slug}", "/users/{id}/orders"},
+//	    RawSampleRate: 0.01,
+//	})
+//	defer agg.Close(context.Background())
+//
+//	agg.Add(vital)
+type VitalAggregator struct {
+	client *Client
+	opts   VitalAggregatorOptions
+	routes *http.ServeMux
+
+	mu          sync.Mutex
+	buckets     map[vitalKey]*DDSketch
+	paths       map[string]bool // unmatched paths bucketed this window
+	windowStart time.Time
+	closed      bool
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+type vitalKey struct {
+	websiteID string
+	path      string
+	metric    VitalMetric
+}
+
+// NewVitalAggregator creates an aggregator and starts its background flush loop.
+// Call Close to stop it and send the final window.
+func NewVitalAggregator(client *Client, opts VitalAggregatorOptions) *VitalAggregator {
+	if opts.Window <= 0 {
+		opts.Window = DefaultVitalAggregationWindow
+	}
+	if opts.MaxPaths <= 0 {
+		opts.MaxPaths = DefaultVitalMaxPaths
+	}
+
+	// A ServeMux matches routes exactly as the application's mux does
+	var routes *http.ServeMux
+	if len(opts.Routes) > 0 {
+		routes = http.NewServeMux()
+		for _, pattern := range opts.Routes {
+			routes.Handle(pattern, http.NotFoundHandler())
+		}
+	}
+
+	a := &VitalAggregator{
+		client:      client,
+		opts:        opts,
+		routes:      routes,
+		buckets:     make(map[vitalKey]*DDSketch),
+		paths:       make(map[string]bool),
+		windowStart: time.Now().UTC(),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go a.loop()
+	return a
+}
+
+// Add records a sample. Samples are validated like TrackVitalWithContext;
+// a sampled fraction is also sent individually per RawSampleRate. After
+// Close, samples are dropped and Add returns ErrAggregatorClosed.
+func (a *VitalAggregator) Add(vital WebVital) error {
+	vital.WebsiteID = a.client.website(vital.WebsiteID)
+	if vital.WebsiteID == "" {
+		return ErrWebsiteIDRequired
+	}
+	if vital.Metric == "" {
+		return ErrVitalMetricRequired
+	}
+	if !vital.Metric.Valid() {
+		return ErrVitalMetricInvalid
+	}
+	if !validVitalValue(vital.Metric, vital.Value) {
+		return ErrVitalValueInvalid
+	}
+
+	path := vital.Path
+	if path == "" && vital.URL != "" {
+		if u, err := url.Parse(vital.URL); err == nil {
+			path = u.Path
+		}
+	}
+	route := a.route(path)
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrAggregatorClosed
+	}
+	if route == "" {
+		route = a.bucketPath(path)
+	}
+	key := vitalKey{websiteID: vital.WebsiteID, path: route, metric: vital.Metric}
+	sketch, ok := a.buckets[key]
+	if !ok {
+		sketch = NewDDSketch(a.opts.RelativeAccuracy)
+		a.buckets[key] = sketch
+	}
+	sketch.Add(vital.Value)
+	a.mu.Unlock()
+
+	if a.opts.RawSampleRate > 0 && rand.Float64() < a.opts.RawSampleRate {
+		go func() {
+			if err := a.client.TrackVital(vital); err != nil && a.opts.OnError != nil {
+				a.opts.OnError(err)
+			}
+		}()
+	}
+	return nil
+}
+
+// route returns the template of the route matching path, if any.
+func (a *VitalAggregator) route(path string) string {
+	if a.routes == nil || path == "" {
+		return ""
+	}
+	_, pattern := a.routes.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}})
+	return routeTemplate(pattern)
+}
+
+// bucketPath returns the path to summarize a sample without a route under:
+// the normalized path, or OtherVitalPath once MaxPaths paths are bucketed
+// in this window. The caller must hold a.mu.
+func (a *VitalAggregator) bucketPath(path string) string {
+	path = defaultPathNormalizer.Normalize(path)
+	if a.paths[path] {
+		return path
+	}
+	if len(a.paths) >= a.opts.MaxPaths {
+		return OtherVitalPath
+	}
+	a.paths[path] = true
+	return path
+}
+
+// Flush sends summaries for the current window and starts a new one.
+func (a *VitalAggregator) Flush(ctx context.Context) error {
+	windowEnd := time.Now().UTC()
+
+	a.mu.Lock()
+	buckets := a.buckets
+	windowStart := a.windowStart
+	a.buckets = make(map[vitalKey]*DDSketch)
+	a.paths = make(map[string]bool)
+	a.windowStart = windowEnd
+	a.mu.Unlock()
+
+	var errs []error
+	for key, sketch := range buckets {
+		p75 := sketch.Quantile(0.75)
+		payload := vitalSummaryPayload{
+			Website:     key.websiteID,
+			Metric:      key.metric,
+			Path:        key.path,
+			Count:       sketch.Count(),
+			Min:         sketch.Min(),
+			Max:         sketch.Max(),
+			Sum:         sketch.Sum(),
+			P50:         sketch.Quantile(0.5),
+			P75:         p75,
+			P95:         sketch.Quantile(0.95),
+			Rating:      a.client.thresholds(key.websiteID, key.metric).Rate(p75),
+			WindowStart: windowStart.Format(time.RFC3339),
+			WindowEnd:   windowEnd.Format(time.RFC3339),
+		}
+		if err := a.client.sendToEndpoint(ctx, vitalSummaryEndpoint, payload, "", ""); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the background flush loop and sends the final window.
+// Samples added after Close are dropped.
+func (a *VitalAggregator) Close(ctx context.Context) error {
+	a.mu.Lock()
+	a.closed = true
+	a.mu.Unlock()
+
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+	<-a.done
+	return a.Flush(ctx)
+}
+
+func (a *VitalAggregator) loop() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.opts.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.Flush(context.Background()); err != nil && a.opts.OnError != nil {
+				a.opts.OnError(err)
+			}
+		case <-a.stop:
+			return
+		}
+	}
+}
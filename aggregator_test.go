@@ -0,0 +1,80 @@
+package entrolytics
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVitalAggregatorBucketsByRoute(t *testing.T) {
+	client, _ := newTestClient(t, ClientOptions{WebsiteID: "site"})
+	agg := NewVitalAggregator(client, VitalAggregatorOptions{
+		Window:   time.Hour,
+		Routes:   []string{"/{$}", "/products/{slug}", "GET /users/{id}/orders"},
+		MaxPaths: 2,
+	})
+	defer agg.Close(context.Background())
+
+	for _, path := range []string{
+		"/",
+		"/products/red-shoes",
+		"/products/blue-hat",
+		"/users/42/orders",
+		"/blog/1",
+		"/blog/2",
+		"/about",
+		"/pricing",
+	} {
+		require.NoError(t, agg.Add(WebVital{Metric: LCP, Value: 1200, Path: path}))
+	}
+
+	agg.mu.Lock()
+	var paths []string
+	counts := make(map[string]uint64)
+	for key, sketch := range agg.buckets {
+		paths = append(paths, key.path)
+		counts[key.path] = sketch.Count()
+	}
+	agg.mu.Unlock()
+	sort.Strings(paths)
+
+	assert.Equal(t, []string{"/", "/about", "/blog/{id}", "/products/{slug}", "/users/{id}/orders", OtherVitalPath}, paths)
+	assert.Equal(t, uint64(2), counts["/products/{slug}"])
+	assert.Equal(t, uint64(2), counts["/blog/{id}"])
+	assert.Equal(t, uint64(1), counts[OtherVitalPath])
+}
+
+func TestVitalAggregatorMaxPathsResetsEachWindow(t *testing.T) {
+	client, _ := newTestClient(t, ClientOptions{WebsiteID: "site"})
+	agg := NewVitalAggregator(client, VitalAggregatorOptions{Window: time.Hour, MaxPaths: 1})
+	defer agg.Close(context.Background())
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, agg.Add(WebVital{Metric: INP, Value: 80, Path: "/page-" + strconv.Itoa(i)}))
+		require.NoError(t, agg.Flush(context.Background()))
+	}
+	require.NoError(t, agg.Add(WebVital{Metric: INP, Value: 80, Path: "/page-3"}))
+
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	assert.Len(t, agg.buckets, 1)
+	assert.Contains(t, agg.buckets, vitalKey{websiteID: "site", path: "/page-3", metric: INP})
+}
+
+func TestVitalAggregatorAddAfterClose(t *testing.T) {
+	client, bodies := newTestClient(t, ClientOptions{WebsiteID: "site"})
+	agg := NewVitalAggregator(client, VitalAggregatorOptions{Window: time.Hour})
+
+	require.NoError(t, agg.Add(WebVital{Metric: CLS, Value: 0.05, Path: "/"}))
+	require.NoError(t, agg.Close(context.Background()))
+	require.Len(t, *bodies, 1)
+
+	assert.ErrorIs(t, agg.Add(WebVital{Metric: CLS, Value: 0.05, Path: "/"}), ErrAggregatorClosed)
+	require.NoError(t, agg.Close(context.Background()))
+	assert.Len(t, *bodies, 1)
+}
@@ -0,0 +1,183 @@
+package entrolytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// DefaultVitalsMaxBodyBytes is the default request body limit for VitalsHandler.
+	DefaultVitalsMaxBodyBytes = 64 << 10
+
+	// DefaultVitalsMaxBatchSize is the default number of metrics accepted per beacon.
+	DefaultVitalsMaxBatchSize = 50
+)
+
+// VitalsHandlerOptions configures the Web Vitals beacon handler.
+type VitalsHandlerOptions struct {
+	// MaxBodyBytes limits the beacon size. Defaults to DefaultVitalsMaxBodyBytes.
+	MaxBodyBytes int64
+
+	// MaxBatchSize limits the number of metrics per beacon.
+	// Defaults to DefaultVitalsMaxBatchSize.
+	MaxBatchSize int
+
+	// GetSessionID is a function to extract session ID from the request.
+	// Takes precedence over a sessionId sent in the beacon.
+	GetSessionID func(r *http.Request) string
+
+	// OnError is called when an error occurs during asynchronous tracking.
+	OnError func(err error)
+}
+
+// vitalBeacon is a metric as reported by the web-vitals library, with the
+// optional page url and sessionId added by the sender.
+type vitalBeacon struct {
+	Name           string                 `json:"name"`
+	Value          float64                `json:"value"`
+	Rating         string                 `json:"rating"`
+	Delta          float64                `json:"delta"`
+	ID             string                 `json:"id"`
+	NavigationType string                 `json:"navigationType"`
+	Attribution    map[string]interface{} `json:"attribution"`
+	URL            string                 `json:"url"`
+	SessionID      string                 `json:"sessionId"`
+}
+
+// VitalsHandler creates an HTTP handler that receives Web Vitals beacons
+// from the browser and forwards them with TrackVitalWithContext, so vitals
+// can be collected first-party through your own domain.
+//
+// The handler accepts POST requests with a single web-vitals metric object
+// or an array of them, as sent by navigator.sendBeacon. Metrics are
+// enriched with the request's IP address, user agent and session, and the
+// page URL defaults to the Referer header. It responds 202 Accepted once
+// the beacon is validated; forwarding happens in the background.
+//
+// Example:
+//
+//	mux.Handle("POST /vitals", entrolytics.VitalsHandler(client, "website_id", entrolytics.VitalsHandlerOptions{}))
+//
+// and in the browser:
+//
+//	onLCP(metric => navigator.sendBeacon('/vitals', JSON.stringify({...metric, url: location.href})))
+func VitalsHandler(client *Client, websiteID string, opts VitalsHandlerOptions) http.Handler {
+	maxBodyBytes := opts.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultVitalsMaxBodyBytes
+	}
+	maxBatchSize := opts.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = DefaultVitalsMaxBatchSize
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		// sendBeacon sends text/plain, so the content type is not checked
+		var beacons []vitalBeacon
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '[' {
+			err = json.Unmarshal(body, &beacons)
+		} else {
+			var beacon vitalBeacon
+			err = json.Unmarshal(body, &beacon)
+			beacons = []vitalBeacon{beacon}
+		}
+		if err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if len(beacons) == 0 || len(beacons) > maxBatchSize {
+			http.Error(w, "invalid batch size", http.StatusBadRequest)
+			return
+		}
+
+		var sessionID string
+		if opts.GetSessionID != nil {
+			sessionID = opts.GetSessionID(r)
+		}
+
+		vitals := make([]WebVital, 0, len(beacons))
+		for _, beacon := range beacons {
+			vital, err := beacon.toWebVital(websiteID, r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if sessionID != "" {
+				vital.SessionID = sessionID
+			}
+			vitals = append(vitals, vital)
+		}
+
+		// The request context is canceled once the response is sent
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			for _, vital := range vitals {
+				if err := client.TrackVitalWithContext(ctx, vital); err != nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+		}()
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// toWebVital validates the beacon and converts it, enriched from the request.
+func (b *vitalBeacon) toWebVital(websiteID string, r *http.Request) (WebVital, error) {
+	metric := VitalMetric(b.Name)
+	if !metric.Valid() {
+		return WebVital{}, ErrVitalMetricInvalid
+	}
+	rating := VitalRating(b.Rating)
+	if rating != "" && !rating.Valid() {
+		return WebVital{}, ErrVitalRatingInvalid
+	}
+
+	pageURL := b.URL
+	if pageURL == "" {
+		pageURL = r.Referer()
+	}
+	var path string
+	if u, err := url.Parse(pageURL); err == nil {
+		path = u.Path
+	}
+
+	return WebVital{
+		WebsiteID:      websiteID,
+		Metric:         metric,
+		Value:          b.Value,
+		Rating:         rating,
+		Delta:          b.Delta,
+		ID:             b.ID,
+		NavigationType: NavigationType(b.NavigationType),
+		Attribution:    b.Attribution,
+		URL:            pageURL,
+		Path:           path,
+		SessionID:      b.SessionID,
+		UserAgent:      r.UserAgent(),
+		IPAddress:      getClientIP(r),
+	}, nil
+}
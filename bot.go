@@ -0,0 +1,147 @@
+package entrolytics
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+//go:embed bots.txt
+var embeddedBotPatterns string
+
+// BotMode controls how the page view middleware handles requests classified as bots.
+type BotMode int
+
+const (
+	// BotTrack tracks bot requests like any other page view (default).
+	BotTrack BotMode = iota
+	// BotSkip does not track bot requests at all.
+	BotSkip
+	// BotTag tracks bot requests with "bot" and "bot_reason" set in the page view Data.
+	BotTag
+	// BotSeparateEvent tracks bot requests as a custom event instead of a page view.
+	BotSeparateEvent
+)
+
+// DefaultBotEventName is the event name used by BotSeparateEvent when none is configured.
+const DefaultBotEventName = "$bot_pageview"
+
+// Bot classification reasons.
+const (
+	BotReasonUserAgent        = "user_agent"
+	BotReasonEmptyUserAgent   = "empty_user_agent"
+	BotReasonNoAcceptLanguage = "missing_accept_language"
+	BotReasonPrefetch         = "prefetch"
+)
+
+// BotClassifier identifies bots, crawlers, uptime checkers and health probes
+// from request headers. It is safe for concurrent use, and its pattern list
+// can be replaced at runtime.
+type BotClassifier struct {
+	mu       sync.RWMutex
+	patterns []string
+
+	// RequireAcceptLanguage classifies requests without an Accept-Language
+	// header as bots. Browsers always send it; most scripts do not.
+	RequireAcceptLanguage bool
+}
+
+var defaultBotClassifier = newDefaultBotClassifier()
+
+func newDefaultBotClassifier() *BotClassifier {
+	b := NewBotClassifier(nil)
+	// The embedded list is a compile-time constant; it cannot fail to parse.
+	_ = b.LoadPatterns(strings.NewReader(embeddedBotPatterns))
+	return b
+}
+
+// DefaultBotClassifier returns the shared classifier built from the embedded pattern list.
+func DefaultBotClassifier() *BotClassifier {
+	return defaultBotClassifier
+}
+
+// NewBotClassifier creates a classifier that matches the given user-agent
+// substrings case-insensitively, with the Accept-Language heuristic enabled.
+func NewBotClassifier(patterns []string) *BotClassifier {
+	b := &BotClassifier{RequireAcceptLanguage: true}
+	b.SetPatterns(patterns)
+	return b
+}
+
+// SetPatterns replaces the user-agent patterns.
+func (b *BotClassifier) SetPatterns(patterns []string) {
+	normalized := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			normalized = append(normalized, p)
+		}
+	}
+
+	b.mu.Lock()
+	b.patterns = normalized
+	b.mu.Unlock()
+}
+
+// LoadPatterns replaces the user-agent patterns with the list read from r:
+// one pattern per line, ignoring blank lines and '#' comments.
+func (b *BotClassifier) LoadPatterns(r io.Reader) error {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	b.SetPatterns(patterns)
+	return nil
+}
+
+// IsBotUserAgent reports whether the user agent matches a known bot pattern.
+func (b *BotClassifier) IsBotUserAgent(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, p := range b.patterns {
+		if strings.Contains(ua, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify reports whether the request comes from a bot and, if so, why.
+func (b *BotClassifier) Classify(r *http.Request) (reason string, isBot bool) {
+	return b.ClassifyHeaders(r.Header.Get)
+}
+
+// ClassifyHeaders is like Classify for frameworks that do not use
+// http.Request. header returns the value of the named request header.
+func (b *BotClassifier) ClassifyHeaders(header func(key string) string) (reason string, isBot bool) {
+	ua := header("User-Agent")
+	if strings.TrimSpace(ua) == "" {
+		return BotReasonEmptyUserAgent, true
+	}
+	if b.IsBotUserAgent(ua) {
+		return BotReasonUserAgent, true
+	}
+	if purpose := header("Sec-Purpose"); strings.HasPrefix(purpose, "prefetch") {
+		return BotReasonPrefetch, true
+	}
+	if purpose := header("Purpose"); purpose == "prefetch" {
+		return BotReasonPrefetch, true
+	}
+	if b.RequireAcceptLanguage && header("Accept-Language") == "" {
+		return BotReasonNoAcceptLanguage, true
+	}
+	return "", false
+}
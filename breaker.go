@@ -0,0 +1,308 @@
+package entrolytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Circuit breaker defaults.
+const (
+	// DefaultCircuitFailureThreshold is the default number of consecutive
+	// failures that open the circuit.
+	DefaultCircuitFailureThreshold = 5
+
+	// DefaultCircuitOpenTimeout is the default time the circuit stays open
+	// before probing.
+	DefaultCircuitOpenTimeout = 30 * time.Second
+)
+
+// CircuitBreakerOptions configures the client's circuit breaker.
+//
+// Rate limit responses, server errors and network errors count as failures;
+// other responses, including client errors, count as successes. After
+// FailureThreshold consecutive failures the circuit opens and requests fail
+// fast with ErrCircuitOpen, or are spooled if SpoolSize is set. After
+// OpenTimeout, or a longer Retry-After, the circuit half-opens and lets
+// HalfOpenProbes requests through: if they all succeed the circuit closes
+// and spooled requests are sent, otherwise it opens again.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that open the
+	// circuit. Defaults to DefaultCircuitFailureThreshold.
+	FailureThreshold int
+
+	// OpenTimeout is how long the circuit stays open before probing.
+	// Defaults to DefaultCircuitOpenTimeout.
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is the number of probe requests that must succeed to
+	// close the circuit. Defaults to 1.
+	HalfOpenProbes int
+
+	// SpoolSize is the number of requests held while the circuit is open,
+	// to be sent once it closes. Spooled calls return nil; requests beyond
+	// it fail with ErrCircuitOpen. Zero fails every request fast.
+	SpoolSize int
+
+	// OnError is called when a spooled request fails to send, is dropped
+	// because the client was disabled or rate limited by the time it was
+	// sent, or is dropped because the circuit reopened while the spool was
+	// full.
+	OnError func(err error)
+}
+
+// CircuitState is the state of the client's circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed sends requests normally.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects or spools requests.
+	CircuitOpen
+	// CircuitHalfOpen lets probe requests through.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type circuitBreaker struct {
+	opts CircuitBreakerOptions
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	openUntil time.Time
+	round     uint64 // incremented each time the circuit half-opens
+	probes    int    // in flight while half-open
+	successes int    // while half-open
+	spooled   []spooledRequest
+	dropped   uint64 // spooled requests dropped for lack of room
+}
+
+// breakerTicket identifies a request admitted by allow, so that record
+// only counts probes of the current half-open round as probes.
+type breakerTicket struct {
+	probe bool
+	round uint64
+}
+
+// spooledRequest is a request held while the circuit is open.
+type spooledRequest struct {
+	endpoint  string
+	body      []byte
+	userAgent string
+	ipAddress string
+}
+
+func newCircuitBreaker(opts *CircuitBreakerOptions) *circuitBreaker {
+	if opts == nil {
+		return nil
+	}
+	b := &circuitBreaker{opts: *opts}
+	if b.opts.FailureThreshold <= 0 {
+		b.opts.FailureThreshold = DefaultCircuitFailureThreshold
+	}
+	if b.opts.OpenTimeout <= 0 {
+		b.opts.OpenTimeout = DefaultCircuitOpenTimeout
+	}
+	if b.opts.HalfOpenProbes <= 0 {
+		b.opts.HalfOpenProbes = 1
+	}
+	return b
+}
+
+// CircuitState returns the state of the circuit breaker. It is always
+// CircuitClosed if no circuit breaker is configured.
+func (c *Client) CircuitState() CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	c.breaker.update(time.Now())
+	return c.breaker.state
+}
+
+// SpoolDropped returns the number of spooled requests dropped because the
+// circuit reopened while the spool was full. It is always zero if no
+// circuit breaker is configured.
+func (c *Client) SpoolDropped() uint64 {
+	if c.breaker == nil {
+		return 0
+	}
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	return c.breaker.dropped
+}
+
+// update half-opens the circuit once its open timeout has passed.
+// The caller must hold b.mu.
+func (b *circuitBreaker) update(now time.Time) {
+	if b.state == CircuitOpen && !now.Before(b.openUntil) {
+		b.state = CircuitHalfOpen
+		b.round++
+		b.probes = 0
+		b.successes = 0
+	}
+}
+
+// allow reports whether a request may be sent. Every allowed request must
+// be followed by a call to record with the returned ticket.
+func (b *circuitBreaker) allow() (breakerTicket, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.update(time.Now())
+	switch b.state {
+	case CircuitClosed:
+		return breakerTicket{}, true
+	case CircuitHalfOpen:
+		if b.probes+b.successes < b.opts.HalfOpenProbes {
+			b.probes++
+			return breakerTicket{probe: true, round: b.round}, true
+		}
+	}
+	return breakerTicket{}, false
+}
+
+// record updates the circuit with the result of an allowed request. It
+// reports whether the circuit closed, so spooled requests can be sent.
+func (b *circuitBreaker) record(t breakerTicket, err error) (closed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed, retryAfter := isCircuitFailure(err)
+	switch b.state {
+	case CircuitClosed:
+		if !failed {
+			b.failures = 0
+			return false
+		}
+		b.failures++
+		if b.failures >= b.opts.FailureThreshold {
+			b.open(retryAfter)
+		}
+	case CircuitHalfOpen:
+		if !t.probe || t.round != b.round {
+			// Admitted before the circuit opened, or a probe of an
+			// earlier round; its result says nothing about this round
+			return false
+		}
+		b.probes--
+		if failed {
+			b.open(retryAfter)
+			return false
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenProbes {
+			b.state = CircuitClosed
+			b.failures = 0
+			return len(b.spooled) > 0
+		}
+	}
+	return false
+}
+
+// open opens the circuit for the open timeout or retryAfter, whichever is
+// longer. The caller must hold b.mu.
+func (b *circuitBreaker) open(retryAfter time.Duration) {
+	b.state = CircuitOpen
+	b.openUntil = time.Now().Add(max(b.opts.OpenTimeout, retryAfter))
+	b.failures = 0
+}
+
+// spool holds a request until the circuit closes, if there is room.
+func (b *circuitBreaker) spool(req spooledRequest) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.spooled) >= b.opts.SpoolSize {
+		return false
+	}
+	b.spooled = append(b.spooled, req)
+	return true
+}
+
+// drainSpool sends the spooled requests through the circuit breaker,
+// spooling them again if the circuit reopens.
+func (c *Client) drainSpool() {
+	b := c.breaker
+	b.mu.Lock()
+	spooled := b.spooled
+	b.spooled = nil
+	b.mu.Unlock()
+
+	for i, req := range spooled {
+		// Spooled requests are subject to Disable and the rate limiter
+		// when they are sent, like any other request
+		err := c.checkEnabled()
+		if err == nil && c.limiter != nil && !c.limiter.allow(req.endpoint) {
+			err = ErrClientRateLimited
+		}
+		if err == nil {
+			ticket, ok := b.allow()
+			if !ok {
+				b.respool(spooled[i:])
+				return
+			}
+			err = c.post(context.Background(), req.endpoint, req.body, req.userAgent, req.ipAddress)
+			b.record(ticket, err)
+		}
+		if err != nil && b.opts.OnError != nil {
+			b.opts.OnError(err)
+		}
+	}
+}
+
+// respool puts unsent requests back at the front of the spool, dropping
+// the newest requests beyond SpoolSize.
+func (b *circuitBreaker) respool(unsent []spooledRequest) {
+	b.mu.Lock()
+	spooled := append(unsent[:len(unsent):len(unsent)], b.spooled...)
+	dropped := len(spooled) - b.opts.SpoolSize
+	if dropped > 0 {
+		spooled = spooled[:b.opts.SpoolSize]
+		b.dropped += uint64(dropped)
+	}
+	b.spooled = spooled
+	b.mu.Unlock()
+
+	if dropped > 0 && b.opts.OnError != nil {
+		b.opts.OnError(fmt.Errorf("%w: dropped %d spooled requests", ErrCircuitOpen, dropped))
+	}
+}
+
+// isCircuitFailure reports whether err indicates that the API is
+// unavailable, and for how long it asked clients to back off.
+func isCircuitFailure(err error) (failed bool, retryAfter time.Duration) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false, 0
+	}
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return true, time.Duration(rateLimitErr.RetryAfter) * time.Second
+	}
+	var networkErr *NetworkError
+	if errors.As(err, &networkErr) {
+		return true, 0
+	}
+	var apiErr *EntrolyticsError
+	if errors.As(err, &apiErr) && apiErr.StatusCode >= http.StatusInternalServerError {
+		return true, 0
+	}
+	return false, 0
+}
@@ -0,0 +1,253 @@
+package entrolytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errServer = &EntrolyticsError{Message: "unavailable", StatusCode: 503}
+
+// breakerStep sends a request through a circuit breaker, or expires its
+// open timeout.
+type breakerStep struct {
+	expire      bool
+	err         error
+	wantAllowed bool
+	wantState   CircuitState
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	send := func(err error, allowed bool, state CircuitState) breakerStep {
+		return breakerStep{err: err, wantAllowed: allowed, wantState: state}
+	}
+	expire := breakerStep{expire: true}
+
+	tests := []struct {
+		name  string
+		opts  CircuitBreakerOptions
+		steps []breakerStep
+	}{
+		{
+			name: "consecutive failures open",
+			opts: CircuitBreakerOptions{FailureThreshold: 2},
+			steps: []breakerStep{
+				send(errServer, true, CircuitClosed),
+				send(errServer, true, CircuitOpen),
+				send(nil, false, CircuitOpen),
+			},
+		},
+		{
+			name: "success resets failures",
+			opts: CircuitBreakerOptions{FailureThreshold: 2},
+			steps: []breakerStep{
+				send(errServer, true, CircuitClosed),
+				send(nil, true, CircuitClosed),
+				send(errServer, true, CircuitClosed),
+			},
+		},
+		{
+			name: "network and rate limit errors are failures",
+			opts: CircuitBreakerOptions{FailureThreshold: 2},
+			steps: []breakerStep{
+				send(&NetworkError{Message: "dial"}, true, CircuitClosed),
+				send(&RateLimitError{}, true, CircuitOpen),
+			},
+		},
+		{
+			name: "client errors and cancellation are not failures",
+			opts: CircuitBreakerOptions{FailureThreshold: 1},
+			steps: []breakerStep{
+				send(&EntrolyticsError{Message: "bad request", StatusCode: 400}, true, CircuitClosed),
+				send(context.Canceled, true, CircuitClosed),
+			},
+		},
+		{
+			name: "successful probe closes",
+			opts: CircuitBreakerOptions{FailureThreshold: 1},
+			steps: []breakerStep{
+				send(errServer, true, CircuitOpen),
+				expire,
+				send(nil, true, CircuitClosed),
+			},
+		},
+		{
+			name: "failed probe reopens",
+			opts: CircuitBreakerOptions{FailureThreshold: 1},
+			steps: []breakerStep{
+				send(errServer, true, CircuitOpen),
+				expire,
+				send(errServer, true, CircuitOpen),
+				send(nil, false, CircuitOpen),
+			},
+		},
+		{
+			name: "all probes must succeed",
+			opts: CircuitBreakerOptions{FailureThreshold: 1, HalfOpenProbes: 2},
+			steps: []breakerStep{
+				send(errServer, true, CircuitOpen),
+				expire,
+				send(nil, true, CircuitHalfOpen),
+				send(nil, true, CircuitClosed),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(&tt.opts)
+			for i, step := range tt.steps {
+				if step.expire {
+					b.openUntil = time.Now()
+					continue
+				}
+				ticket, ok := b.allow()
+				require.Equal(t, step.wantAllowed, ok, "step %d", i)
+				if ok {
+					b.record(ticket, step.err)
+				}
+				assert.Equal(t, step.wantState, b.state, "step %d", i)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHonorsRetryAfter(t *testing.T) {
+	b := newCircuitBreaker(&CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Millisecond})
+	ticket, _ := b.allow()
+	b.record(ticket, &RateLimitError{RetryAfter: 60})
+
+	assert.Equal(t, CircuitOpen, b.state)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), b.openUntil, time.Second)
+}
+
+func TestCircuitBreakerIgnoresStaleResults(t *testing.T) {
+	b := newCircuitBreaker(&CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Millisecond})
+
+	// Admitted while closed; its result lands after the circuit half-opens
+	stale, ok := b.allow()
+	require.True(t, ok)
+
+	failing, ok := b.allow()
+	require.True(t, ok)
+	b.record(failing, errServer)
+	assert.Equal(t, CircuitOpen, b.state)
+
+	time.Sleep(2 * time.Millisecond)
+	probe, ok := b.allow()
+	require.True(t, ok)
+	assert.True(t, probe.probe)
+
+	assert.False(t, b.record(stale, nil))
+	assert.Equal(t, CircuitHalfOpen, b.state)
+	assert.Equal(t, 1, b.probes)
+
+	_, ok = b.allow()
+	assert.False(t, ok, "only one probe may be in flight")
+
+	b.record(probe, nil)
+	assert.Equal(t, CircuitClosed, b.state)
+	assert.Equal(t, 0, b.probes)
+}
+
+func TestCircuitBreakerIgnoresProbesOfEarlierRounds(t *testing.T) {
+	b := newCircuitBreaker(&CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Millisecond, HalfOpenProbes: 2})
+	first, _ := b.allow()
+	b.record(first, errServer)
+
+	time.Sleep(2 * time.Millisecond)
+	slowProbe, ok := b.allow()
+	require.True(t, ok)
+	failedProbe, ok := b.allow()
+	require.True(t, ok)
+	b.record(failedProbe, errServer)
+	assert.Equal(t, CircuitOpen, b.state)
+
+	time.Sleep(2 * time.Millisecond)
+	probe, ok := b.allow()
+	require.True(t, ok)
+
+	b.record(slowProbe, nil)
+	assert.Equal(t, 1, b.probes)
+	assert.Equal(t, 0, b.successes)
+
+	b.record(probe, nil)
+	assert.Equal(t, CircuitHalfOpen, b.state)
+	assert.Equal(t, 1, b.successes)
+}
+
+func TestCircuitBreakerRespoolRespectsSpoolSize(t *testing.T) {
+	var reported []error
+	client := NewClientWithOptions(ClientOptions{
+		APIKey: "ent_test",
+		CircuitBreaker: &CircuitBreakerOptions{
+			SpoolSize: 2,
+			OnError:   func(err error) { reported = append(reported, err) },
+		},
+	})
+	b := client.breaker
+	b.state = CircuitOpen
+	b.openUntil = time.Now().Add(time.Hour)
+
+	// Requests spooled while the drain was in progress
+	b.spooled = []spooledRequest{{endpoint: "/new"}}
+	b.respool([]spooledRequest{{endpoint: "/a"}, {endpoint: "/b"}})
+
+	assert.Equal(t, []spooledRequest{{endpoint: "/a"}, {endpoint: "/b"}}, b.spooled)
+	assert.Equal(t, uint64(1), client.SpoolDropped())
+	require.Len(t, reported, 1)
+	assert.True(t, errors.Is(reported[0], ErrCircuitOpen))
+
+	client.drainSpool()
+	assert.Len(t, b.spooled, 2)
+	assert.Equal(t, uint64(1), client.SpoolDropped())
+}
+
+func TestCircuitBreakerDrainSpoolChecksClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         ClientOptions
+		disable      bool
+		wantSent     int
+		wantReported []error
+		wantDropped  uint64
+	}{
+		{
+			name:         "disabled",
+			disable:      true,
+			wantReported: []error{ErrClientDisabled, ErrClientDisabled},
+			wantDropped:  2,
+		},
+		{
+			name:         "rate limited",
+			opts:         ClientOptions{RateLimiter: &RateLimiterOptions{Rate: 1}},
+			wantSent:     1,
+			wantReported: []error{ErrClientRateLimited},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported []error
+			tt.opts.CircuitBreaker = &CircuitBreakerOptions{
+				SpoolSize: 2,
+				OnError:   func(err error) { reported = append(reported, err) },
+			}
+			client, bodies := newTestClient(t, tt.opts)
+			client.breaker.spooled = []spooledRequest{{endpoint: "/api/send"}, {endpoint: "/api/send"}}
+			if tt.disable {
+				client.Disable()
+			}
+
+			client.drainSpool()
+			assert.Empty(t, client.breaker.spooled)
+			assert.Len(t, *bodies, tt.wantSent)
+			assert.Equal(t, tt.wantReported, reported)
+			assert.Equal(t, tt.wantDropped, client.Dropped())
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package entrolytics
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Campaign holds marketing attribution parsed from a landing page URL.
+type Campaign struct {
+	// Source is the utm_source parameter, e.g. "google" or "newsletter".
+	Source string
+
+	// Medium is the utm_medium parameter, e.g. "cpc" or "email".
+	Medium string
+
+	// Name is the utm_campaign parameter.
+	Name string
+
+	// Term is the utm_term parameter, typically the paid search keyword.
+	Term string
+
+	// Content is the utm_content parameter, used to differentiate ads.
+	Content string
+
+	// ID is the utm_id parameter.
+	ID string
+
+	// GCLID is the Google Ads click ID.
+	GCLID string
+
+	// FBCLID is the Meta (Facebook) click ID.
+	FBCLID string
+
+	// MSCLKID is the Microsoft Advertising click ID.
+	MSCLKID string
+}
+
+// ParseCampaign extracts UTM parameters and ad click IDs from a query string.
+// Other query parameters are ignored.
+func ParseCampaign(query url.Values) Campaign {
+	return Campaign{
+		Source:  query.Get("utm_source"),
+		Medium:  query.Get("utm_medium"),
+		Name:    query.Get("utm_campaign"),
+		Term:    query.Get("utm_term"),
+		Content: query.Get("utm_content"),
+		ID:      query.Get("utm_id"),
+		GCLID:   query.Get("gclid"),
+		FBCLID:  query.Get("fbclid"),
+		MSCLKID: query.Get("msclkid"),
+	}
+}
+
+// CampaignFromRequest extracts campaign attribution from the request URL.
+//
+// Example:
+//
+//	client.Track(entrolytics.Event{
+//	    WebsiteID: "abc123",
+//	    Name:      "signup",
+//	    Campaign:  entrolytics.CampaignFromRequest(r),
+//	})
+func CampaignFromRequest(r *http.Request) Campaign {
+	return ParseCampaign(r.URL.Query())
+}
+
+// FirstTouchCampaign returns the campaign stored in the named cookie by the
+// page view middleware (see MiddlewareOptions.CampaignCookie), falling back to
+// the request URL when the cookie is absent.
+func FirstTouchCampaign(r *http.Request, cookieName string) Campaign {
+	if cookie, err := r.Cookie(cookieName); err == nil {
+		if query, err := url.ParseQuery(cookie.Value); err == nil {
+			if campaign := ParseCampaign(query); !campaign.IsZero() {
+				return campaign
+			}
+		}
+	}
+	return CampaignFromRequest(r)
+}
+
+// IsZero reports whether no campaign parameters are set.
+func (c Campaign) IsZero() bool {
+	return c == Campaign{}
+}
+
+// Encode serializes the campaign as a query string, as stored in the
+// campaign cookie. ParseCampaign reverses it.
+func (c Campaign) Encode() string {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   c.Source,
+		"utm_medium":   c.Medium,
+		"utm_campaign": c.Name,
+		"utm_term":     c.Term,
+		"utm_content":  c.Content,
+		"utm_id":       c.ID,
+		"gclid":        c.GCLID,
+		"fbclid":       c.FBCLID,
+		"msclkid":      c.MSCLKID,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return query.Encode()
+}
+
+// payload returns the wire representation, or nil if no parameters are set.
+func (c Campaign) payload() *campaignPayload {
+	if c.IsZero() {
+		return nil
+	}
+	return &campaignPayload{
+		Source:  c.Source,
+		Medium:  c.Medium,
+		Name:    c.Name,
+		Term:    c.Term,
+		Content: c.Content,
+		ID:      c.ID,
+		GCLID:   c.GCLID,
+		FBCLID:  c.FBCLID,
+		MSCLKID: c.MSCLKID,
+	}
+}
+
+// ResolveCampaign returns the campaign attribution for the request when
+// TrackCampaign is enabled. With CampaignCookie set, it stores first-touch
+// attribution in the cookie, and attributes requests without campaign
+// parameters to the stored campaign. It must be called before the response
+// headers are written.
+func (opts *MiddlewareOptions) ResolveCampaign(w http.ResponseWriter, r *http.Request) Campaign {
+	if !opts.TrackCampaign {
+		return Campaign{}
+	}
+
+	campaign := CampaignFromRequest(r)
+	if opts.CampaignCookie == "" {
+		return campaign
+	}
+	if campaign.IsZero() {
+		return FirstTouchCampaign(r, opts.CampaignCookie)
+	}
+
+	// Keep the first touch if one is already stored
+	if _, err := r.Cookie(opts.CampaignCookie); err != nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     opts.CampaignCookie,
+			Value:    campaign.Encode(),
+			Path:     "/",
+			MaxAge:   opts.CampaignCookieMaxAge,
+			Secure:   r.TLS != nil,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	return campaign
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	entrolytics "github.com/entrolytics/go"
+)
+
+// runDeploy registers the deployment detected from the environment, with
+// any fields given as flags taking precedence.
+func runDeploy(ctx context.Context, args []string) error {
+	fs, cfg := newFlagSet("deploy", "")
+	id := fs.String("id", "", "deployment ID (default detected from the environment)")
+	sha := fs.String("sha", "", "git commit SHA (default detected)")
+	branch := fs.String("branch", "", "git branch (default detected)")
+	url := fs.String("url", "", "deployment URL (default detected)")
+	source := fs.String("source", "", "deployment platform, e.g. vercel or custom (default detected)")
+	if err := cfg.parse(args, 0); err != nil {
+		return err
+	}
+	if err := cfg.requireWebsite(); err != nil {
+		return err
+	}
+	client, err := cfg.client()
+	if err != nil {
+		return err
+	}
+
+	deploy, err := entrolytics.DetectDeployment()
+	if err != nil && !(errors.Is(err, entrolytics.ErrDeploymentNotDetected) && *id != "") {
+		return fmt.Errorf("%w; set -id", err)
+	}
+	deploy.WebsiteID = cfg.opts.WebsiteID
+	setIfNotEmpty(&deploy.DeployID, *id)
+	setIfNotEmpty(&deploy.GitSha, *sha)
+	setIfNotEmpty(&deploy.GitBranch, *branch)
+	setIfNotEmpty(&deploy.DeployURL, *url)
+	if *source != "" {
+		deploy.Source = entrolytics.DeploymentSource(*source)
+	}
+	if deploy.Source == "" {
+		deploy.Source = entrolytics.Custom
+	}
+
+	if err := client.SetDeploymentWithContext(ctx, deploy); err != nil {
+		return err
+	}
+	fmt.Printf("registered deployment %s (%s)\n", deploy.DeployID, deploy.Source)
+	return nil
+}
+
+// runTrack sends a custom event.
+func runTrack(ctx context.Context, args []string) error {
+	fs, cfg := newFlagSet("track", "<name>")
+	url := fs.String("url", "", "page URL")
+	referrer := fs.String("referrer", "", "referrer URL")
+	userID := fs.String("user", "", "user ID")
+	sessionID := fs.String("session", "", "session ID")
+	data := fs.String("data", "", `event data as a JSON object, e.g. '{"plan":"pro"}'`)
+	if err := cfg.parse(args, 1); err != nil {
+		return err
+	}
+	if err := cfg.requireWebsite(); err != nil {
+		return err
+	}
+	eventData, err := parseObject("data", *data)
+	if err != nil {
+		return err
+	}
+	client, err := cfg.client()
+	if err != nil {
+		return err
+	}
+
+	return client.TrackWithContext(ctx, entrolytics.Event{
+		WebsiteID: cfg.opts.WebsiteID,
+		Name:      fs.Arg(0),
+		Data:      eventData,
+		URL:       *url,
+		Referrer:  *referrer,
+		UserID:    *userID,
+		SessionID: *sessionID,
+	})
+}
+
+// runIdentify identifies a user with optional traits.
+func runIdentify(ctx context.Context, args []string) error {
+	fs, cfg := newFlagSet("identify", "<user-id>")
+	traits := fs.String("traits", "", `user traits as a JSON object, e.g. '{"plan":"pro"}'`)
+	if err := cfg.parse(args, 1); err != nil {
+		return err
+	}
+	if err := cfg.requireWebsite(); err != nil {
+		return err
+	}
+	userTraits, err := parseObject("traits", *traits)
+	if err != nil {
+		return err
+	}
+	client, err := cfg.client()
+	if err != nil {
+		return err
+	}
+
+	return client.IdentifyWithContext(ctx, entrolytics.Identify{
+		WebsiteID: cfg.opts.WebsiteID,
+		UserID:    fs.Arg(0),
+		Traits:    userTraits,
+	})
+}
+
+func setIfNotEmpty(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
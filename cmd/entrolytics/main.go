@@ -0,0 +1,164 @@
+// Command entrolytics sends events, identifies users, registers deployments
+// and replays captured events from the command line.
+//
+// Usage:
+//
+//	entrolytics <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	deploy     register the current deployment
+//	track      send a custom event
+//	identify   identify a user
+//	replay     send the events in a JSON Lines file
+//
+// Common flags override the ENTROLYTICS_* environment variables and the
+// config file named by ENTROLYTICS_CONFIG, as read by
+// entrolytics.LoadClientOptions after the flags are parsed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	entrolytics "github.com/entrolytics/go"
+)
+
+const usage = `Usage: entrolytics <command> [flags] [arguments]
+
+Commands:
+  deploy               register the current deployment
+  track <name>         send a custom event
+  identify <user-id>   identify a user
+  replay <file.jsonl>  send the events in a JSON Lines file ("-" for stdin)
+
+Run "entrolytics <command> -h" for the flags of a command.
+`
+
+// errUsage is returned for invalid command lines, after the usage is printed.
+var errUsage = errors.New("invalid usage")
+
+type command func(ctx context.Context, args []string) error
+
+var commands = map[string]command{
+	"deploy":   runDeploy,
+	"track":    runTrack,
+	"identify": runIdentify,
+	"replay":   runReplay,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "entrolytics: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd(ctx, os.Args[2:]); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "entrolytics: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// config holds the client options shared by all commands.
+type config struct {
+	fs    *flag.FlagSet
+	flags entrolytics.ClientOptions // common flag values
+	opts  entrolytics.ClientOptions // loaded by parse
+}
+
+// newFlagSet creates a flag set for a command with the common flags.
+// Flags are parsed with config.parse.
+func newFlagSet(name, args string) (*flag.FlagSet, *config) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: entrolytics %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+
+	cfg := &config{fs: fs}
+	fs.StringVar(&cfg.flags.APIKey, "api-key", "", "API key (env "+entrolytics.EnvAPIKey+")")
+	fs.StringVar(&cfg.flags.Host, "host", "", "API host (env "+entrolytics.EnvHost+", default "+entrolytics.DefaultHost+")")
+	fs.StringVar(&cfg.flags.WebsiteID, "website", "", "website ID (env "+entrolytics.EnvWebsiteID+")")
+	fs.DurationVar(&cfg.flags.Timeout, "timeout", 0, "request timeout (env "+entrolytics.EnvTimeout+", default "+entrolytics.DefaultTimeout.String()+")")
+	fs.BoolVar(&cfg.flags.Debug, "debug", false, "log requests (env "+entrolytics.EnvDebug+")")
+	return fs, cfg
+}
+
+// parse parses the flags and checks the number of positional arguments,
+// then loads the client options from the environment and overrides them
+// with the flags that were set. The environment is read last so that -h
+// works even if it is invalid.
+func (cfg *config) parse(args []string, n int) error {
+	if err := cfg.fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.fs.NArg() != n {
+		cfg.fs.Usage()
+		return errUsage
+	}
+
+	opts, err := entrolytics.LoadClientOptions()
+	if err != nil {
+		return err
+	}
+	cfg.fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "api-key":
+			opts.APIKey = cfg.flags.APIKey
+		case "host":
+			opts.Host = cfg.flags.Host
+		case "website":
+			opts.WebsiteID = cfg.flags.WebsiteID
+		case "timeout":
+			opts.Timeout = cfg.flags.Timeout
+		case "debug":
+			opts.Debug = cfg.flags.Debug
+		}
+	})
+	cfg.opts = opts
+	return nil
+}
+
+// client creates an Entrolytics client from the config.
+func (cfg *config) client() (*entrolytics.Client, error) {
+	if cfg.opts.APIKey == "" && !cfg.opts.Disabled {
+		return nil, errors.New("API key is required: set -api-key or " + entrolytics.EnvAPIKey)
+	}
+	return entrolytics.NewClientWithOptions(cfg.opts), nil
+}
+
+// requireWebsite returns an error if no website ID is configured.
+func (cfg *config) requireWebsite() error {
+	if cfg.opts.WebsiteID == "" {
+		return errors.New("website ID is required: set -website or " + entrolytics.EnvWebsiteID)
+	}
+	return nil
+}
+
+// parseObject decodes a JSON object flag value. An empty value is a nil map.
+func parseObject(name, value string) (map[string]interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &obj); err != nil {
+		return nil, fmt.Errorf("-%s must be a JSON object: %w", name, err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	entrolytics "github.com/entrolytics/go"
+)
+
+// replayRecord is one line of a replay file. Type is "event" (the default),
+// "pageview" or "identify"; Website defaults to the -website flag.
+type replayRecord struct {
+	Type      string                 `json:"type"`
+	Website   string                 `json:"website"`
+	Name      string                 `json:"name"`
+	URL       string                 `json:"url"`
+	Referrer  string                 `json:"referrer"`
+	Title     string                 `json:"title"`
+	Data      map[string]interface{} `json:"data"`
+	UserID    string                 `json:"userId"`
+	SessionID string                 `json:"sessionId"`
+	Traits    map[string]interface{} `json:"traits"`
+	UserAgent string                 `json:"userAgent"`
+	IPAddress string                 `json:"ip"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// replayStats counts the outcome of a replay.
+type replayStats struct {
+	sent, failed int
+	start        time.Time
+}
+
+func (s *replayStats) String() string {
+	elapsed := time.Since(s.start)
+	return fmt.Sprintf("%d sent, %d failed in %s (%.1f/s)",
+		s.sent, s.failed, elapsed.Round(time.Millisecond), float64(s.sent+s.failed)/elapsed.Seconds())
+}
+
+// runReplay sends the records of a JSON Lines file at a limited rate,
+// reporting progress and failures on stderr.
+func runReplay(ctx context.Context, args []string) error {
+	fs, cfg := newFlagSet("replay", "<file.jsonl>")
+	rate := fs.Float64("rate", 10, "maximum records sent per second; 0 for no limit")
+	progress := fs.Duration("progress", 5*time.Second, "interval between progress reports; 0 to disable")
+	stopOnError := fs.Bool("stop-on-error", false, "stop at the first failed record")
+	if err := cfg.parse(args, 1); err != nil {
+		return err
+	}
+	client, err := cfg.client()
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var limit <-chan time.Time
+	if *rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+		defer ticker.Stop()
+		limit = ticker.C
+	}
+	var report <-chan time.Time
+	if *progress > 0 {
+		ticker := time.NewTicker(*progress)
+		defer ticker.Stop()
+		report = ticker.C
+	}
+
+	stats := &replayStats{start: time.Now()}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64<<10), 10<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		if limit != nil {
+			select {
+			case <-limit:
+			case <-ctx.Done():
+				fmt.Fprintf(os.Stderr, "interrupted: %s\n", stats)
+				return ctx.Err()
+			}
+		}
+		select {
+		case <-report:
+			fmt.Fprintf(os.Stderr, "progress: %s\n", stats)
+		default:
+		}
+
+		if err := replayLine(ctx, client, cfg.opts.WebsiteID, scanner.Bytes()); err != nil {
+			stats.failed++
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", line, err)
+			if *stopOnError || ctx.Err() != nil {
+				fmt.Fprintf(os.Stderr, "stopped: %s\n", stats)
+				return fmt.Errorf("replay stopped at line %d", line)
+			}
+			continue
+		}
+		stats.sent++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "done: %s\n", stats)
+	if stats.failed > 0 {
+		return fmt.Errorf("%d records failed", stats.failed)
+	}
+	return nil
+}
+
+// replayLine decodes a record and sends it with the matching client method.
+func replayLine(ctx context.Context, client *entrolytics.Client, websiteID string, line []byte) error {
+	var rec replayRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if rec.Website == "" {
+		rec.Website = websiteID
+	}
+
+	switch rec.Type {
+	case "", "event":
+		return client.TrackWithContext(ctx, entrolytics.Event{
+			WebsiteID: rec.Website,
+			Name:      rec.Name,
+			Data:      rec.Data,
+			URL:       rec.URL,
+			Referrer:  rec.Referrer,
+			UserID:    rec.UserID,
+			SessionID: rec.SessionID,
+			UserAgent: rec.UserAgent,
+			IPAddress: rec.IPAddress,
+			Timestamp: rec.Timestamp,
+		})
+	case "pageview":
+		return client.PageViewWithContext(ctx, entrolytics.PageView{
+			WebsiteID: rec.Website,
+			URL:       rec.URL,
+			Referrer:  rec.Referrer,
+			Title:     rec.Title,
+			Data:      rec.Data,
+			UserID:    rec.UserID,
+			SessionID: rec.SessionID,
+			UserAgent: rec.UserAgent,
+			IPAddress: rec.IPAddress,
+			Timestamp: rec.Timestamp,
+		})
+	case "identify":
+		return client.IdentifyWithContext(ctx, entrolytics.Identify{
+			WebsiteID: rec.Website,
+			UserID:    rec.UserID,
+			Traits:    rec.Traits,
+			Timestamp: rec.Timestamp,
+		})
+	default:
+		return fmt.Errorf("unknown record type %q", rec.Type)
+	}
+}
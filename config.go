@@ -0,0 +1,313 @@
+package entrolytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by LoadClientOptions.
+const (
+	EnvAPIKey    = "ENTROLYTICS_API_KEY"
+	EnvHost      = "ENTROLYTICS_HOST"
+	EnvWebsiteID = "ENTROLYTICS_WEBSITE_ID"
+	EnvTimeout   = "ENTROLYTICS_TIMEOUT"
+	EnvEndpoint  = "ENTROLYTICS_ENDPOINT"
+	EnvDebug     = "ENTROLYTICS_DEBUG"
+	EnvDisabled  = "ENTROLYTICS_DISABLED"
+
+	// EnvConfig names a YAML or JSON config file to load before the
+	// other variables.
+	EnvConfig = "ENTROLYTICS_CONFIG"
+)
+
+// ConfigError is returned when a setting has an invalid value.
+type ConfigError struct {
+	// Setting names the offending setting: an environment variable, or a
+	// config file key with the file name, e.g. "timeout in entrolytics.yaml".
+	Setting string
+
+	// Err describes what is wrong with the value.
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("entrolytics: invalid %s: %v", e.Setting, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// fileConfig is the format of a config file. Keys match the ClientOptions
+// field names in camelCase; timeout is a duration like "5s" or a number of
+// seconds.
+type fileConfig struct {
+	APIKey    *string
+	Host      *string
+	WebsiteID *string
+	Timeout   *fileTimeout
+	Endpoint  *string
+	UserAgent *string
+	Debug     *bool
+	Disabled  *bool
+}
+
+// fields maps config file keys to the fields they set. Keys are decoded
+// one at a time so that errors name the key.
+func (fc *fileConfig) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"apiKey":    &fc.APIKey,
+		"host":      &fc.Host,
+		"websiteId": &fc.WebsiteID,
+		"timeout":   &fc.Timeout,
+		"endpoint":  &fc.Endpoint,
+		"userAgent": &fc.UserAgent,
+		"debug":     &fc.Debug,
+		"disabled":  &fc.Disabled,
+	}
+}
+
+// fileTimeout is a timeout written as a duration string or a number of
+// seconds. It is checked by setTimeout.
+type fileTimeout string
+
+func (t *fileTimeout) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = fileTimeout(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return errors.New("must be a duration string or a number of seconds")
+	}
+	*t = fileTimeout(n)
+	return nil
+}
+
+func (t *fileTimeout) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return errors.New("must be a duration string or a number of seconds")
+	}
+	*t = fileTimeout(node.Value)
+	return nil
+}
+
+// NewClientFromEnv creates a client from LoadClientOptions. Unless the
+// client is disabled, ENTROLYTICS_API_KEY (or apiKey in the config file)
+// is required.
+//
+// Example:
+//
+//	client, err := entrolytics.NewClientFromEnv()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewClientFromEnv() (*Client, error) {
+	opts, err := LoadClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	if opts.APIKey == "" && !opts.Disabled {
+		return nil, &ConfigError{Setting: EnvAPIKey, Err: errors.New("API key is required")}
+	}
+	return NewClientWithOptions(opts), nil
+}
+
+// LoadClientOptions reads client options from the ENTROLYTICS_*
+// environment variables. If ENTROLYTICS_CONFIG names a file, it is loaded
+// first with LoadClientOptionsFile and the environment overrides it.
+// Invalid values are reported as a *ConfigError.
+func LoadClientOptions() (ClientOptions, error) {
+	var opts ClientOptions
+	if path := os.Getenv(EnvConfig); path != "" {
+		var err error
+		if opts, err = LoadClientOptionsFile(path); err != nil {
+			return ClientOptions{}, err
+		}
+	}
+	if err := opts.applyEnv(os.LookupEnv); err != nil {
+		return ClientOptions{}, err
+	}
+	return opts, nil
+}
+
+// LoadClientOptionsFile reads client options from a YAML or JSON file.
+// Files ending in .json are parsed as JSON, others as YAML.
+//
+// Example file:
+//
+//	apiKey: ent_xxx
+//	websiteId: abc123
+//	endpoint: /api/send-native
+//	timeout: 5s
+func LoadClientOptionsFile(path string) (ClientOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ClientOptions{}, &ConfigError{Setting: EnvConfig, Err: err}
+	}
+
+	// Decode each value separately so that errors name the key
+	values := make(map[string]func(v interface{}) error)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var raw map[string]json.RawMessage
+		err = json.Unmarshal(data, &raw)
+		for key, value := range raw {
+			values[key] = func(v interface{}) error { return json.Unmarshal(value, v) }
+		}
+	} else {
+		var raw map[string]yaml.Node
+		if err = yaml.NewDecoder(bytes.NewReader(data)).Decode(&raw); errors.Is(err, io.EOF) {
+			err = nil // empty file
+		}
+		for key, value := range raw {
+			values[key] = value.Decode
+		}
+	}
+	if err != nil {
+		return ClientOptions{}, &ConfigError{Setting: "config file " + path, Err: err}
+	}
+
+	var fc fileConfig
+	name := filepath.Base(path)
+	fields := fc.fields()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			return ClientOptions{}, &ConfigError{Setting: key + " in " + name, Err: errors.New("unknown setting")}
+		}
+		if err := values[key](field); err != nil {
+			return ClientOptions{}, &ConfigError{Setting: key + " in " + name, Err: err}
+		}
+	}
+
+	var opts ClientOptions
+	settings := []struct {
+		key   string
+		value *string
+		set   func(setting, value string) error
+	}{
+		{"apiKey", fc.APIKey, opts.setAPIKey},
+		{"host", fc.Host, opts.setHost},
+		{"websiteId", fc.WebsiteID, opts.setWebsiteID},
+		{"timeout", (*string)(fc.Timeout), opts.setTimeout},
+		{"endpoint", fc.Endpoint, opts.setEndpoint},
+		{"userAgent", fc.UserAgent, opts.setUserAgent},
+	}
+	for _, s := range settings {
+		if s.value != nil {
+			if err := s.set(s.key+" in "+name, *s.value); err != nil {
+				return ClientOptions{}, err
+			}
+		}
+	}
+	if fc.Debug != nil {
+		opts.Debug = *fc.Debug
+	}
+	if fc.Disabled != nil {
+		opts.Disabled = *fc.Disabled
+	}
+	return opts, nil
+}
+
+// applyEnv overrides options with the environment variables that are set.
+func (opts *ClientOptions) applyEnv(lookupEnv func(string) (string, bool)) error {
+	settings := []struct {
+		env string
+		set func(setting, value string) error
+	}{
+		{EnvAPIKey, opts.setAPIKey},
+		{EnvHost, opts.setHost},
+		{EnvWebsiteID, opts.setWebsiteID},
+		{EnvTimeout, opts.setTimeout},
+		{EnvEndpoint, opts.setEndpoint},
+		{EnvDebug, func(setting, value string) error { return setBool(&opts.Debug, setting, value) }},
+		{EnvDisabled, func(setting, value string) error { return setBool(&opts.Disabled, setting, value) }},
+	}
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok && value != "" {
+			if err := s.set(s.env, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (opts *ClientOptions) setAPIKey(_, value string) error {
+	opts.APIKey = value
+	return nil
+}
+
+func (opts *ClientOptions) setWebsiteID(_, value string) error {
+	opts.WebsiteID = value
+	return nil
+}
+
+func (opts *ClientOptions) setUserAgent(_, value string) error {
+	opts.UserAgent = value
+	return nil
+}
+
+func (opts *ClientOptions) setHost(setting, value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return &ConfigError{Setting: setting, Err: err}
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ConfigError{Setting: setting, Err: fmt.Errorf("%q is not an http or https URL", value)}
+	}
+	opts.Host = strings.TrimSuffix(value, "/")
+	return nil
+}
+
+func (opts *ClientOptions) setEndpoint(setting, value string) error {
+	if !strings.HasPrefix(value, "/") {
+		return &ConfigError{Setting: setting, Err: fmt.Errorf("%q must start with /", value)}
+	}
+	opts.Endpoint = value
+	return nil
+}
+
+// setTimeout accepts a duration such as "5s", or a number of seconds.
+func (opts *ClientOptions) setTimeout(setting, value string) error {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, serr := strconv.ParseFloat(value, 64)
+		if serr != nil {
+			return &ConfigError{Setting: setting, Err: fmt.Errorf("%q is not a duration", value)}
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+	if timeout <= 0 {
+		return &ConfigError{Setting: setting, Err: fmt.Errorf("%q must be positive", value)}
+	}
+	opts.Timeout = timeout
+	return nil
+}
+
+func setBool(field *bool, setting, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return &ConfigError{Setting: setting, Err: fmt.Errorf("%q is not a boolean", value)}
+	}
+	*field = b
+	return nil
+}
@@ -0,0 +1,52 @@
+package entrolytics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadClientOptionsFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		wantTimeout time.Duration
+		wantSetting string
+	}{
+		{name: "json seconds", file: "c.json", content: `{"timeout": 5}`, wantTimeout: 5 * time.Second},
+		{name: "json fractional seconds", file: "c.json", content: `{"timeout": 1.5}`, wantTimeout: 1500 * time.Millisecond},
+		{name: "json duration", file: "c.json", content: `{"timeout": "250ms"}`, wantTimeout: 250 * time.Millisecond},
+		{name: "yaml seconds", file: "c.yaml", content: "timeout: 5\n", wantTimeout: 5 * time.Second},
+		{name: "yaml duration", file: "c.yaml", content: "timeout: 2m\n", wantTimeout: 2 * time.Minute},
+		{name: "empty yaml", file: "c.yaml", content: ""},
+		{name: "json bad timeout", file: "c.json", content: `{"timeout": true}`, wantSetting: "timeout in c.json"},
+		{name: "yaml bad timeout", file: "c.yaml", content: "timeout: [5]\n", wantSetting: "timeout in c.yaml"},
+		{name: "negative timeout", file: "c.json", content: `{"timeout": -1}`, wantSetting: "timeout in c.json"},
+		{name: "json bad type", file: "c.json", content: `{"apiKey": "k", "debug": "yes"}`, wantSetting: "debug in c.json"},
+		{name: "yaml bad type", file: "c.yaml", content: "disabled: maybe\n", wantSetting: "disabled in c.yaml"},
+		{name: "unknown key", file: "c.yaml", content: "apiKey: k\ntimout: 5\n", wantSetting: "timout in c.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			opts, err := LoadClientOptionsFile(path)
+			if tt.wantSetting != "" {
+				var configErr *ConfigError
+				require.True(t, errors.As(err, &configErr), "got %v", err)
+				assert.Equal(t, tt.wantSetting, configErr.Setting)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTimeout, opts.Timeout)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package entrolytics
+
+import (
+	"context"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// deploymentDetector reads a platform's deployment from its environment variables.
+type deploymentDetector struct {
+	source DeploymentSource
+
+	// markers are variables of which at least one is set on the platform
+	markers []string
+
+	// Candidate variables for each field, in order of preference
+	deployID  []string
+	gitSha    []string
+	gitBranch []string
+	deployURL []string
+
+	// host builds the deployment URL from the environment when no
+	// deployURL variable is set
+	host func(getenv func(string) string) string
+}
+
+// deploymentDetectors are checked in order. ENTROLYTICS_* variables come
+// first so they can override any platform.
+var deploymentDetectors = []deploymentDetector{
+	{
+		source:    Custom,
+		markers:   []string{"ENTROLYTICS_DEPLOY_ID", "ENTROLYTICS_GIT_SHA"},
+		deployID:  []string{"ENTROLYTICS_DEPLOY_ID"},
+		gitSha:    []string{"ENTROLYTICS_GIT_SHA"},
+		gitBranch: []string{"ENTROLYTICS_GIT_BRANCH"},
+		deployURL: []string{"ENTROLYTICS_DEPLOY_URL"},
+	},
+	{
+		source:    Vercel,
+		markers:   []string{"VERCEL"},
+		deployID:  []string{"VERCEL_DEPLOYMENT_ID"},
+		gitSha:    []string{"VERCEL_GIT_COMMIT_SHA"},
+		gitBranch: []string{"VERCEL_GIT_COMMIT_REF"},
+		deployURL: []string{"VERCEL_URL"},
+	},
+	{
+		source:    Netlify,
+		markers:   []string{"NETLIFY"},
+		deployID:  []string{"DEPLOY_ID"},
+		gitSha:    []string{"COMMIT_REF"},
+		gitBranch: []string{"BRANCH", "HEAD"},
+		deployURL: []string{"DEPLOY_URL"},
+	},
+	{
+		source:    Cloudflare,
+		markers:   []string{"CF_PAGES"},
+		gitSha:    []string{"CF_PAGES_COMMIT_SHA"},
+		gitBranch: []string{"CF_PAGES_BRANCH"},
+		deployURL: []string{"CF_PAGES_URL"},
+	},
+	{
+		source:    Railway,
+		markers:   []string{"RAILWAY_ENVIRONMENT", "RAILWAY_DEPLOYMENT_ID"},
+		deployID:  []string{"RAILWAY_DEPLOYMENT_ID"},
+		gitSha:    []string{"RAILWAY_GIT_COMMIT_SHA"},
+		gitBranch: []string{"RAILWAY_GIT_BRANCH"},
+		deployURL: []string{"RAILWAY_PUBLIC_DOMAIN"},
+	},
+	{
+		source:    Render,
+		markers:   []string{"RENDER"},
+		deployID:  []string{"RENDER_DEPLOY_ID"},
+		gitSha:    []string{"RENDER_GIT_COMMIT"},
+		gitBranch: []string{"RENDER_GIT_BRANCH"},
+		deployURL: []string{"RENDER_EXTERNAL_URL"},
+	},
+	{
+		source:   Fly,
+		markers:  []string{"FLY_APP_NAME"},
+		deployID: []string{"FLY_IMAGE_REF", "FLY_MACHINE_VERSION"},
+		gitSha:   []string{"GIT_SHA", "SOURCE_COMMIT"},
+		host: func(getenv func(string) string) string {
+			if app := getenv("FLY_APP_NAME"); app != "" {
+				return app + ".fly.dev"
+			}
+			return ""
+		},
+	},
+	{
+		source:    Heroku,
+		markers:   []string{"DYNO"},
+		deployID:  []string{"HEROKU_RELEASE_VERSION"},
+		gitSha:    []string{"HEROKU_SLUG_COMMIT", "SOURCE_VERSION"},
+		deployURL: []string{"HEROKU_APP_DEFAULT_DOMAIN_NAME"},
+	},
+	{
+		// CodeBuild, Amplify and Lambda
+		source:    AWS,
+		markers:   []string{"CODEBUILD_BUILD_ID", "AWS_JOB_ID", "AWS_LAMBDA_FUNCTION_NAME"},
+		deployID:  []string{"CODEBUILD_BUILD_ID", "AWS_JOB_ID", "AWS_LAMBDA_FUNCTION_VERSION"},
+		gitSha:    []string{"CODEBUILD_RESOLVED_SOURCE_VERSION", "AWS_COMMIT_ID"},
+		gitBranch: []string{"CODEBUILD_WEBHOOK_HEAD_REF", "AWS_BRANCH"},
+	},
+	{
+		// Cloud Run, App Engine and Cloud Build
+		source:    GCP,
+		markers:   []string{"K_REVISION", "GAE_DEPLOYMENT_ID"},
+		deployID:  []string{"K_REVISION", "GAE_DEPLOYMENT_ID", "BUILD_ID"},
+		gitSha:    []string{"COMMIT_SHA"},
+		gitBranch: []string{"BRANCH_NAME"},
+	},
+	{
+		// App Service and Azure Pipelines
+		source:    Azure,
+		markers:   []string{"WEBSITE_SITE_NAME", "TF_BUILD"},
+		deployID:  []string{"WEBSITE_DEPLOYMENT_ID", "BUILD_BUILDID"},
+		gitSha:    []string{"SCM_COMMIT_ID", "BUILD_SOURCEVERSION"},
+		gitBranch: []string{"BUILD_SOURCEBRANCH"},
+		deployURL: []string{"WEBSITE_HOSTNAME"},
+	},
+}
+
+// DetectDeployment builds a Deployment from the environment variables of
+// the platform the process runs on (see DeploymentSource). Variables named
+// ENTROLYTICS_DEPLOY_ID, ENTROLYTICS_GIT_SHA, ENTROLYTICS_GIT_BRANCH and
+// ENTROLYTICS_DEPLOY_URL take precedence and are reported as Custom.
+//
+// The commit SHA falls back to the VCS revision embedded by the Go
+// toolchain, and is used as the deployment ID when the platform provides
+// none. Without a platform, the build's VCS revision alone is reported as a
+// Custom deployment. It returns ErrDeploymentNotDetected if no
+// deployment ID can be determined. WebsiteID is left empty.
+func DetectDeployment() (Deployment, error) {
+	return detectDeployment(os.Getenv)
+}
+
+func detectDeployment(getenv func(string) string) (Deployment, error) {
+	var deploy Deployment
+	for _, d := range deploymentDetectors {
+		if firstEnv(getenv, d.markers) == "" {
+			continue
+		}
+		deploy = Deployment{
+			Source:    d.source,
+			DeployID:  firstEnv(getenv, d.deployID),
+			GitSha:    firstEnv(getenv, d.gitSha),
+			GitBranch: strings.TrimPrefix(firstEnv(getenv, d.gitBranch), "refs/heads/"),
+			DeployURL: firstEnv(getenv, d.deployURL),
+		}
+		if deploy.DeployURL == "" && d.host != nil {
+			deploy.DeployURL = d.host(getenv)
+		}
+		break
+	}
+
+	if deploy.GitSha == "" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			deploy.GitSha = buildRevision(info)
+		}
+	}
+	if deploy.DeployID == "" {
+		deploy.DeployID = deploy.GitSha
+	}
+	if deploy.DeployID == "" {
+		return Deployment{}, ErrDeploymentNotDetected
+	}
+	if deploy.Source == "" {
+		deploy.Source = Custom
+	}
+	if deploy.DeployURL != "" && !strings.Contains(deploy.DeployURL, "://") {
+		deploy.DeployURL = "https://" + deploy.DeployURL
+	}
+	return deploy, nil
+}
+
+// RegisterDeploymentFromEnv detects the current deployment with
+// DetectDeployment and registers it with SetDeploymentWithContext. Call it
+// once at startup. It returns the registered deployment.
+//
+// Example:
+//
+//	if _, err := client.RegisterDeploymentFromEnv(ctx, "website_id"); err != nil {
+//	    log.Printf("deployment not registered: %v", err)
+//	}
+func (c *Client) RegisterDeploymentFromEnv(ctx context.Context, websiteID string) (Deployment, error) {
+	deploy, err := DetectDeployment()
+	if err != nil {
+		return Deployment{}, err
+	}
+	deploy.WebsiteID = websiteID
+	return deploy, c.SetDeploymentWithContext(ctx, deploy)
+}
+
+// buildRevision returns the VCS revision the binary was built from, with a
+// "-dirty" suffix if the working tree had local modifications.
+func buildRevision(info *debug.BuildInfo) string {
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// firstEnv returns the first non-empty value of the named variables.
+func firstEnv(getenv func(string) string, names []string) string {
+	for _, name := range names {
+		if value := getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+// Package entroecho provides Echo middleware for Entrolytics analytics.
+//
+// Basic usage:
+//
+//	client := entrolytics.NewClient("ent_xxx")
+//
+//	e := echo.New()
+//	e.Use(entroecho.PageView(client, "website_id"))
+//	e.HTTPErrorHandler = entroecho.ErrorHandler(client, "website_id", e.DefaultHTTPErrorHandler)
+package entroecho
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	entrolytics "github.com/entrolytics/go"
+	"github.com/labstack/echo/v4"
+)
+
+// trackerKey is the Echo context key holding the request-scoped tracker.
+const trackerKey = "entrolytics.tracker"
+
+// Options configures the Echo page view middleware.
+type Options struct {
+	entrolytics.MiddlewareOptions
+
+	// UserID extracts the user ID from the Echo context.
+	// Takes precedence over MiddlewareOptions.GetUserID.
+	UserID func(c echo.Context) string
+
+	// SessionID extracts the session ID from the Echo context.
+	// Takes precedence over MiddlewareOptions.GetSessionID.
+	SessionID func(c echo.Context) string
+}
+
+// PageView creates Echo middleware that automatically tracks page views.
+//
+// Example:
+//
+//	e := echo.New()
+//	e.Use(entroecho.PageView(client, "website_id"))
+func PageView(client *entrolytics.Client, websiteID string) echo.MiddlewareFunc {
+	return PageViewWithOptions(client, websiteID, Options{})
+}
+
+// PageViewWithOptions creates Echo middleware with custom options. Route
+// templates come from c.Path() when UseRoutePattern is set, and the client
+// IP from c.RealIP(), which honors the Echo#IPExtractor trust configuration.
+//
+// Errors returned by the handler are passed to the Echo HTTP error handler
+// before tracking, so the page view records the final response status.
+func PageViewWithOptions(client *entrolytics.Client, websiteID string, opts Options) echo.MiddlewareFunc {
+	botClassifier := opts.BotClassifier
+	if botClassifier == nil {
+		botClassifier = entrolytics.DefaultBotClassifier()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+
+			tracker := entrolytics.NewTracker(client, websiteID, r)
+			tracker.IPAddress = c.RealIP()
+			tracker.UserID = userID(c, &opts)
+			tracker.SessionID = sessionID(c, &opts)
+			c.Set(trackerKey, tracker)
+			r = r.WithContext(entrolytics.NewContext(r.Context(), tracker))
+			c.SetRequest(r)
+
+			// Only track GET requests to tracked paths
+			if r.Method != http.MethodGet || opts.Skip(r.URL.Path) {
+				return next(c)
+			}
+
+			info := entrolytics.RequestInfo{
+				Path:      r.URL.Path,
+				RawQuery:  r.URL.RawQuery,
+				Referrer:  tracker.Referrer,
+				UserAgent: tracker.UserAgent,
+				IPAddress: tracker.IPAddress,
+				UserID:    tracker.UserID,
+				SessionID: tracker.SessionID,
+				Campaign:  opts.ResolveCampaign(c.Response(), r),
+			}
+			tracker.Campaign = info.Campaign
+
+			if opts.BotMode != entrolytics.BotTrack {
+				info.BotReason, _ = botClassifier.Classify(r)
+			}
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Commit the error response so its status is known. The
+				// error is still returned to outer middleware; echo's
+				// error handler skips responses that are already committed.
+				c.Error(err)
+			}
+			info.Duration = time.Since(start)
+
+			info.Status = c.Response().Status
+			info.Bytes = c.Response().Size
+
+			info.Route = c.Path()
+			if opts.GetRoutePattern != nil {
+				if route := opts.GetRoutePattern(r); route != "" {
+					info.Route = route
+				}
+			}
+
+			entrolytics.TrackRequest(client, websiteID, opts.MiddlewareOptions, info)
+			return err
+		}
+	}
+}
+
+// ErrorHandler wraps an Echo HTTP error handler to track failed requests
+// (status 500 and above) as entrolytics.ErrorEventName events before
+// delegating to next. Events include the error message, truncated to
+// entrolytics.DefaultMaxMessageSize. Errors for responses that are already
+// committed are passed to next without tracking, as Echo calls the error
+// handler again for errors that PageView has already handled.
+//
+// Example:
+//
+//	e.HTTPErrorHandler = entroecho.ErrorHandler(client, "website_id", e.DefaultHTTPErrorHandler)
+func ErrorHandler(client *entrolytics.Client, websiteID string, next echo.HTTPErrorHandler) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			next(err, c)
+			return
+		}
+
+		status := http.StatusInternalServerError
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			status = httpErr.Code
+		}
+
+		if status >= 500 {
+			tracker := FromContext(c)
+			if tracker == nil {
+				tracker = entrolytics.NewTracker(client, websiteID, c.Request())
+				tracker.IPAddress = c.RealIP()
+			}
+			event := *tracker
+
+			errorType := "http_" + strconv.Itoa(status)
+			if httpErr == nil {
+				errorType = errorTypeOf(err)
+			}
+			data := map[string]interface{}{
+				"route":       c.Path(),
+				"method":      c.Request().Method,
+				"status":      status,
+				"error_type":  errorType,
+				"message":     entrolytics.TruncateMessage(err.Error(), entrolytics.DefaultMaxMessageSize),
+				"fingerprint": entrolytics.Fingerprint(errorType, []string{c.Path()}),
+			}
+
+			// Track error (non-blocking)
+			go func() {
+				_ = event.Track(entrolytics.ErrorEventName, data)
+			}()
+		}
+
+		next(err, c)
+	}
+}
+
+// TrackEvent creates Echo middleware that tracks a custom event once the
+// handler has run. Use it on specific routes:
+//
+//	e.POST("/checkout", checkoutHandler, entroecho.TrackEvent(client, "website_id", "checkout", nil))
+func TrackEvent(client *entrolytics.Client, websiteID, eventName string, getData func(c echo.Context) map[string]interface{}) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			var data map[string]interface{}
+			if getData != nil {
+				data = getData(c)
+			}
+
+			tracker := FromContext(c)
+			if tracker == nil {
+				tracker = entrolytics.NewTracker(client, websiteID, c.Request())
+				tracker.IPAddress = c.RealIP()
+			}
+			event := *tracker
+
+			// Track event (non-blocking)
+			go func() {
+				_ = event.Track(eventName, data)
+			}()
+
+			return err
+		}
+	}
+}
+
+// FromContext returns the request-scoped tracker stored by the page view
+// middleware, or nil if the middleware is not installed. The tracker is also
+// stored in the request context, for entrolytics.FromContext(c.Request().Context()).
+func FromContext(c echo.Context) *entrolytics.Tracker {
+	if tracker, ok := c.Get(trackerKey).(*entrolytics.Tracker); ok {
+		return tracker
+	}
+	return nil
+}
+
+func errorTypeOf(err error) string {
+	// Unwrap to the root cause so wrapped errors group together
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			break
+		}
+		err = next
+	}
+	return fmt.Sprintf("%T", err)
+}
+
+func userID(c echo.Context, opts *Options) string {
+	if opts.UserID != nil {
+		return opts.UserID(c)
+	}
+	if opts.GetUserID != nil {
+		return opts.GetUserID(c.Request())
+	}
+	return ""
+}
+
+func sessionID(c echo.Context, opts *Options) string {
+	if opts.SessionID != nil {
+		return opts.SessionID(c)
+	}
+	if opts.GetSessionID != nil {
+		return opts.GetSessionID(c.Request())
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package entroecho
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	entrolytics "github.com/entrolytics/go"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandlerWithPageViewTracksOnce(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+	client := entrolytics.NewClientWithOptions(entrolytics.ClientOptions{APIKey: "ent_test", Host: srv.URL})
+
+	e := echo.New()
+	e.Use(PageViewWithOptions(client, "site", Options{}))
+	e.HTTPErrorHandler = ErrorHandler(client, "site", e.DefaultHTTPErrorHandler)
+	e.GET("/fail", func(c echo.Context) error {
+		return errors.New("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	count := func(substr string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		for _, body := range bodies {
+			if strings.Contains(body, substr) {
+				n++
+			}
+		}
+		return n
+	}
+	require.Eventually(t, func() bool { return count(`"$error"`) > 0 && count(`"/fail"`) > 1 }, time.Second, 10*time.Millisecond)
+	// Give a duplicate time to arrive
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, count(`"$error"`))
+	assert.Equal(t, 2, count(`"/fail"`))
+}
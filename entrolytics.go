@@ -40,8 +40,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -49,6 +52,12 @@ const (
 	// DefaultHost is the default Entrolytics API host.
 	DefaultHost = "https://entrolytics.click"
 
+	// DefaultEndpoint is the default collection endpoint for events and page views.
+	DefaultEndpoint = "/api/send"
+
+	// PageViewEventName is the event name page views are sent as.
+	PageViewEventName = "$pageview"
+
 	// DefaultTimeout is the default HTTP request timeout.
 	DefaultTimeout = 10 * time.Second
 
@@ -56,13 +65,35 @@ const (
 	Version = "2.1.0"
 )
 
+// Endpoints for Phase 2 data.
+const (
+	vitalsEndpoint       = "/api/collect/vitals"
+	vitalSummaryEndpoint = "/api/collect/vitals/summary"
+	formsEndpoint        = "/api/collect/forms"
+)
+
 // Client is the Entrolytics API client.
 type Client struct {
-	apiKey    string
-	host      string
-	timeout   time.Duration
-	userAgent string
-	http      *http.Client
+	apiKey            string
+	host              string
+	hostname          string // of host, so outbound tracking can skip it
+	endpoint          string
+	websiteID         string
+	timeout           time.Duration
+	userAgent         string
+	debug             bool
+	disabled          bool
+	enabled           atomic.Bool
+	dropped           atomic.Uint64
+	samplingRules     []SamplingRule
+	dataLimits        DataLimits
+	trackingPlan      *TrackingPlan
+	schemaMode        SchemaMode
+	onSchemaViolation func(err *SchemaValidationError)
+	limiter           *rateLimiter
+	breaker           *circuitBreaker
+	vitalThresholds   map[string]map[VitalMetric]VitalThresholds
+	http              *http.Client
 }
 
 // NewClient creates a new Entrolytics client with the given API key.
@@ -77,6 +108,9 @@ func NewClientWithOptions(opts ClientOptions) *Client {
 	if opts.Host == "" {
 		opts.Host = DefaultHost
 	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultEndpoint
+	}
 	if opts.Timeout == 0 {
 		opts.Timeout = DefaultTimeout
 	}
@@ -84,15 +118,32 @@ func NewClientWithOptions(opts ClientOptions) *Client {
 		opts.UserAgent = fmt.Sprintf("entrolytics-go/%s", Version)
 	}
 
-	return &Client{
-		apiKey:    opts.APIKey,
-		host:      opts.Host,
-		timeout:   opts.Timeout,
-		userAgent: opts.UserAgent,
+	c := &Client{
+		apiKey:            opts.APIKey,
+		host:              opts.Host,
+		endpoint:          opts.Endpoint,
+		websiteID:         opts.WebsiteID,
+		timeout:           opts.Timeout,
+		userAgent:         opts.UserAgent,
+		debug:             opts.Debug,
+		disabled:          opts.Disabled,
+		samplingRules:     opts.SamplingRules,
+		dataLimits:        opts.DataLimits,
+		trackingPlan:      opts.TrackingPlan,
+		schemaMode:        opts.SchemaMode,
+		onSchemaViolation: opts.OnSchemaViolation,
+		limiter:           newRateLimiter(opts.RateLimiter),
+		breaker:           newCircuitBreaker(opts.CircuitBreaker),
+		vitalThresholds:   opts.VitalThresholds,
 		http: &http.Client{
 			Timeout: opts.Timeout,
 		},
 	}
+	if u, err := url.Parse(opts.Host); err == nil {
+		c.hostname = u.Hostname()
+	}
+	c.enabled.Store(true)
+	return c
 }
 
 // Track sends a custom event to Entrolytics.
@@ -102,15 +153,46 @@ func (c *Client) Track(event Event) error {
 
 // TrackWithContext sends a custom event with context for cancellation.
 func (c *Client) TrackWithContext(ctx context.Context, event Event) error {
-	if c.apiKey == "" {
+	if err := c.checkEnabled(); err != nil {
+		return err
+	}
+	if c.apiKey == "" && !c.disabled {
 		return ErrAPIKeyRequired
 	}
+	event.WebsiteID = c.website(event.WebsiteID)
 	if event.WebsiteID == "" {
 		return ErrWebsiteIDRequired
 	}
 	if event.Name == "" {
 		return ErrEventNameRequired
 	}
+	data, err := NormalizeData("data", event.Data, c.dataLimits)
+	if err != nil {
+		return err
+	}
+	props := event.Properties
+	if err := props.validate("data", c.dataLimits); err != nil {
+		return err
+	}
+	if c.trackingPlan != nil && props.Len() > 0 {
+		// The plan validates the combined data, so merge it up front
+		if data, err = mergeProperties(data, props); err != nil {
+			return err
+		}
+		props = nil
+	}
+	event.Data = data
+	if data, err = c.applySchema(event); err != nil {
+		return err
+	}
+	eventData, err := payloadData(data, props)
+	if err != nil {
+		return err
+	}
+	sampleRate, keep := c.sample(event.WebsiteID, event.Name, c.endpoint, event.UserID, event.SessionID)
+	if !keep {
+		return nil
+	}
 
 	timestamp := event.Timestamp
 	if timestamp.IsZero() {
@@ -120,14 +202,16 @@ func (c *Client) TrackWithContext(ctx context.Context, event Event) error {
 	payload := eventPayload{
 		Type: "event",
 		Payload: trackPayload{
-			Website:   event.WebsiteID,
-			Name:      event.Name,
-			Data:      event.Data,
-			URL:       event.URL,
-			Referrer:  event.Referrer,
-			UserID:    event.UserID,
-			SessionID: event.SessionID,
-			Timestamp: timestamp.Format(time.RFC3339),
+			Website:    event.WebsiteID,
+			Name:       event.Name,
+			Data:       eventData,
+			URL:        event.URL,
+			Referrer:   event.Referrer,
+			UserID:     event.UserID,
+			SessionID:  event.SessionID,
+			Campaign:   event.Campaign.payload(),
+			SampleRate: sampleRate,
+			Timestamp:  timestamp.Format(time.RFC3339),
 		},
 	}
 
@@ -141,37 +225,65 @@ func (c *Client) PageView(pv PageView) error {
 
 // PageViewWithContext sends a page view with context for cancellation.
 func (c *Client) PageViewWithContext(ctx context.Context, pv PageView) error {
-	if c.apiKey == "" {
+	if err := c.checkEnabled(); err != nil {
+		return err
+	}
+	if c.apiKey == "" && !c.disabled {
 		return ErrAPIKeyRequired
 	}
+	pv.WebsiteID = c.website(pv.WebsiteID)
 	if pv.WebsiteID == "" {
 		return ErrWebsiteIDRequired
 	}
 	if pv.URL == "" {
 		return ErrURLRequired
 	}
+	sampleRate, keep := c.sample(pv.WebsiteID, PageViewEventName, c.endpoint, pv.UserID, pv.SessionID)
+	if !keep {
+		return nil
+	}
 
 	timestamp := pv.Timestamp
 	if timestamp.IsZero() {
 		timestamp = time.Now().UTC()
 	}
 
-	data := make(map[string]interface{})
+	data, err := NormalizeData("data", pv.Data, c.dataLimits)
+	if err != nil {
+		return err
+	}
+	props := pv.Properties
+	if err := props.validate("data", c.dataLimits); err != nil {
+		return err
+	}
 	if pv.Title != "" {
-		data["title"] = pv.Title
+		if props.Len() > 0 {
+			props = NewProperties().Merge(props).String("title", pv.Title)
+		} else {
+			if data == nil {
+				data = make(map[string]interface{}, 1)
+			}
+			data["title"] = pv.Title
+		}
+	}
+	eventData, err := payloadData(data, props)
+	if err != nil {
+		return err
 	}
 
 	payload := eventPayload{
 		Type: "event",
 		Payload: trackPayload{
-			Website:   pv.WebsiteID,
-			Name:      "$pageview",
-			Data:      data,
-			URL:       pv.URL,
-			Referrer:  pv.Referrer,
-			UserID:    pv.UserID,
-			SessionID: pv.SessionID,
-			Timestamp: timestamp.Format(time.RFC3339),
+			Website:    pv.WebsiteID,
+			Name:       PageViewEventName,
+			Data:       eventData,
+			URL:        pv.URL,
+			Referrer:   pv.Referrer,
+			UserID:     pv.UserID,
+			SessionID:  pv.SessionID,
+			Campaign:   pv.Campaign.payload(),
+			SampleRate: sampleRate,
+			Timestamp:  timestamp.Format(time.RFC3339),
 		},
 	}
 
@@ -185,15 +297,30 @@ func (c *Client) Identify(id Identify) error {
 
 // IdentifyWithContext sends user identification with context for cancellation.
 func (c *Client) IdentifyWithContext(ctx context.Context, id Identify) error {
-	if c.apiKey == "" {
+	if err := c.checkEnabled(); err != nil {
+		return err
+	}
+	if c.apiKey == "" && !c.disabled {
 		return ErrAPIKeyRequired
 	}
+	id.WebsiteID = c.website(id.WebsiteID)
 	if id.WebsiteID == "" {
 		return ErrWebsiteIDRequired
 	}
 	if id.UserID == "" {
 		return ErrUserIDRequired
 	}
+	traits, err := NormalizeData("traits", id.Traits, c.dataLimits)
+	if err != nil {
+		return err
+	}
+	if err := id.TraitProperties.validate("traits", c.dataLimits); err != nil {
+		return err
+	}
+	traitsData, err := payloadData(traits, id.TraitProperties)
+	if err != nil {
+		return err
+	}
 
 	timestamp := id.Timestamp
 	if timestamp.IsZero() {
@@ -205,7 +332,7 @@ func (c *Client) IdentifyWithContext(ctx context.Context, id Identify) error {
 		Payload: identifyPayload{
 			Website:   id.WebsiteID,
 			UserID:    id.UserID,
-			Traits:    id.Traits,
+			Traits:    traitsData,
 			Timestamp: timestamp.Format(time.RFC3339),
 		},
 	}
@@ -225,17 +352,44 @@ func (c *Client) TrackVital(vital WebVital) error {
 
 // TrackVitalWithContext sends a Web Vital metric with context for cancellation.
 func (c *Client) TrackVitalWithContext(ctx context.Context, vital WebVital) error {
-	if c.apiKey == "" {
+	if err := c.checkEnabled(); err != nil {
+		return err
+	}
+	if c.apiKey == "" && !c.disabled {
 		return ErrAPIKeyRequired
 	}
+	vital.WebsiteID = c.website(vital.WebsiteID)
 	if vital.WebsiteID == "" {
 		return ErrWebsiteIDRequired
 	}
 	if vital.Metric == "" {
 		return ErrVitalMetricRequired
 	}
+	if !vital.Metric.Valid() {
+		return ErrVitalMetricInvalid
+	}
+	if !validVitalValue(vital.Metric, vital.Value) {
+		return ErrVitalValueInvalid
+	}
 	if vital.Rating == "" {
-		return ErrVitalRatingRequired
+		vital.Rating = c.thresholds(vital.WebsiteID, vital.Metric).Rate(vital.Value)
+	} else if !vital.Rating.Valid() {
+		return ErrVitalRatingInvalid
+	}
+	attribution, err := NormalizeData("attribution", vital.Attribution, c.dataLimits)
+	if err != nil {
+		return err
+	}
+	if err := vital.AttributionProperties.validate("attribution", c.dataLimits); err != nil {
+		return err
+	}
+	attributionData, err := payloadData(attribution, vital.AttributionProperties)
+	if err != nil {
+		return err
+	}
+	sampleRate, keep := c.sample(vital.WebsiteID, string(vital.Metric), vitalsEndpoint, "", vital.SessionID)
+	if !keep {
+		return nil
 	}
 
 	payload := vitalPayload{
@@ -246,13 +400,14 @@ func (c *Client) TrackVitalWithContext(ctx context.Context, vital WebVital) erro
 		Delta:          vital.Delta,
 		ID:             vital.ID,
 		NavigationType: vital.NavigationType,
-		Attribution:    vital.Attribution,
+		Attribution:    attributionData,
 		URL:            vital.URL,
 		Path:           vital.Path,
 		SessionID:      vital.SessionID,
+		SampleRate:     sampleRate,
 	}
 
-	return c.sendToEndpoint(ctx, "/api/collect/vitals", payload, "", "")
+	return c.sendToEndpoint(ctx, vitalsEndpoint, payload, vital.UserAgent, vital.IPAddress)
 }
 
 // ============================================================================
@@ -267,9 +422,13 @@ func (c *Client) TrackFormEvent(event FormEvent) error {
 
 // TrackFormEventWithContext sends a form event with context for cancellation.
 func (c *Client) TrackFormEventWithContext(ctx context.Context, event FormEvent) error {
-	if c.apiKey == "" {
+	if err := c.checkEnabled(); err != nil {
+		return err
+	}
+	if c.apiKey == "" && !c.disabled {
 		return ErrAPIKeyRequired
 	}
+	event.WebsiteID = c.website(event.WebsiteID)
 	if event.WebsiteID == "" {
 		return ErrWebsiteIDRequired
 	}
@@ -282,6 +441,10 @@ func (c *Client) TrackFormEventWithContext(ctx context.Context, event FormEvent)
 	if event.URLPath == "" {
 		return ErrURLPathRequired
 	}
+	sampleRate, keep := c.sample(event.WebsiteID, string(event.EventType), formsEndpoint, "", event.SessionID)
+	if !keep {
+		return nil
+	}
 
 	payload := formEventPayload{
 		Website:        event.WebsiteID,
@@ -296,10 +459,13 @@ func (c *Client) TrackFormEventWithContext(ctx context.Context, event FormEvent)
 		TimeSinceStart: event.TimeSinceStart,
 		ErrorMessage:   event.ErrorMessage,
 		Success:        event.Success,
+		FieldCount:     event.FieldCount,
+		FieldNames:     event.FieldNames,
 		SessionID:      event.SessionID,
+		SampleRate:     sampleRate,
 	}
 
-	return c.sendToEndpoint(ctx, "/api/collect/forms", payload, "", "")
+	return c.sendToEndpoint(ctx, formsEndpoint, payload, "", "")
 }
 
 // ============================================================================
@@ -314,9 +480,13 @@ func (c *Client) SetDeployment(deploy Deployment) error {
 
 // SetDeploymentWithContext registers deployment with context for cancellation.
 func (c *Client) SetDeploymentWithContext(ctx context.Context, deploy Deployment) error {
-	if c.apiKey == "" {
+	if err := c.checkEnabled(); err != nil {
+		return err
+	}
+	if c.apiKey == "" && !c.disabled {
 		return ErrAPIKeyRequired
 	}
+	deploy.WebsiteID = c.website(deploy.WebsiteID)
 	if deploy.WebsiteID == "" {
 		return ErrWebsiteIDRequired
 	}
@@ -336,18 +506,74 @@ func (c *Client) SetDeploymentWithContext(ctx context.Context, deploy Deployment
 	return c.sendToEndpoint(ctx, fmt.Sprintf("/api/websites/%s/deployments", deploy.WebsiteID), payload, "", "")
 }
 
+// trackAsync sends a custom event in the background, passing any error to onError.
+func (c *Client) trackAsync(ctx context.Context, event Event, onError func(err error)) {
+	go func() {
+		if err := c.TrackWithContext(ctx, event); err != nil && onError != nil {
+			onError(err)
+		}
+	}()
+}
+
 // send performs the HTTP request to the Entrolytics API.
 func (c *Client) send(ctx context.Context, payload interface{}, userAgent, ipAddress string) error {
-	return c.sendToEndpoint(ctx, "/api/send", payload, userAgent, ipAddress)
+	return c.sendToEndpoint(ctx, c.endpoint, payload, userAgent, ipAddress)
+}
+
+// website returns websiteID, or the client's default website ID if empty.
+func (c *Client) website(websiteID string) string {
+	if websiteID == "" {
+		return c.websiteID
+	}
+	return websiteID
+}
+
+// checkEnabled returns ErrClientDisabled, and counts the call as dropped,
+// while Disable is in effect, so calls are skipped before any work is done.
+func (c *Client) checkEnabled() error {
+	if !c.enabled.Load() {
+		c.dropped.Add(1)
+		return ErrClientDisabled
+	}
+	return nil
 }
 
 // sendToEndpoint performs the HTTP request to a specific endpoint.
 func (c *Client) sendToEndpoint(ctx context.Context, endpoint string, payload interface{}, userAgent, ipAddress string) error {
+	if c.disabled {
+		return nil
+	}
+	if err := c.checkEnabled(); err != nil {
+		return err
+	}
+
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return &NetworkError{Message: "failed to marshal payload", Err: err}
 	}
 
+	if c.limiter != nil && !c.limiter.allow(endpoint) {
+		return ErrClientRateLimited
+	}
+	if c.breaker == nil {
+		return c.post(ctx, endpoint, body, userAgent, ipAddress)
+	}
+	ticket, ok := c.breaker.allow()
+	if !ok {
+		if c.breaker.spool(spooledRequest{endpoint, body, userAgent, ipAddress}) {
+			return nil
+		}
+		return ErrCircuitOpen
+	}
+	err = c.post(ctx, endpoint, body, userAgent, ipAddress)
+	if c.breaker.record(ticket, err) {
+		go c.drainSpool()
+	}
+	return err
+}
+
+// post sends a JSON body to an endpoint.
+func (c *Client) post(ctx context.Context, endpoint string, body []byte, userAgent, ipAddress string) error {
 	url := fmt.Sprintf("%s%s", c.host, endpoint)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
@@ -365,15 +591,26 @@ func (c *Client) sendToEndpoint(ctx context.Context, endpoint string, payload in
 		req.Header.Set("X-Forwarded-For", ipAddress)
 	}
 
+	// Debug logs never include the body, which may hold user IDs and traits
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return &NetworkError{Message: "request failed", Err: err}
+		err = &NetworkError{Message: "request failed", Err: err}
+		c.logf("POST %s (%d bytes): %v", endpoint, len(body), err)
+		return err
 	}
 	defer resp.Body.Close()
 
+	c.logf("POST %s (%d bytes): %d", endpoint, len(body), resp.StatusCode)
 	return c.handleResponse(resp)
 }
 
+// logf logs a debug message if debug logging is enabled.
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.debug {
+		log.Printf("entrolytics: "+format, args...)
+	}
+}
+
 // handleResponse processes the API response.
 func (c *Client) handleResponse(resp *http.Response) error {
 	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
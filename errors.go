@@ -42,11 +42,39 @@ var (
 	}
 
 	// ErrVitalRatingRequired is returned when the vital rating is missing.
+	//
+	// Deprecated: Ratings are derived from the value when missing, so this
+	// error is no longer returned.
 	ErrVitalRatingRequired = &EntrolyticsError{
 		Code:    "vital_rating_required",
 		Message: "vital rating is required (good, needs-improvement, or poor)",
 	}
 
+	// ErrVitalMetricInvalid is returned when the vital metric type is not supported.
+	ErrVitalMetricInvalid = &EntrolyticsError{
+		Code:    "vital_metric_invalid",
+		Message: "vital metric type must be one of LCP, INP, CLS, TTFB, or FCP",
+	}
+
+	// ErrVitalRatingInvalid is returned when the vital rating is not supported.
+	ErrVitalRatingInvalid = &EntrolyticsError{
+		Code:    "vital_rating_invalid",
+		Message: "vital rating must be one of good, needs-improvement, or poor",
+	}
+
+	// ErrVitalValueInvalid is returned when the vital value is out of range for its metric.
+	ErrVitalValueInvalid = &EntrolyticsError{
+		Code:    "vital_value_invalid",
+		Message: "vital value must be a non-negative number within the metric's range",
+	}
+
+	// ErrAggregatorClosed is returned when adding a sample to a closed
+	// VitalAggregator. The sample is dropped.
+	ErrAggregatorClosed = &EntrolyticsError{
+		Code:    "aggregator_closed",
+		Message: "vital aggregator is closed",
+	}
+
 	// Phase 2: Form Analytics errors
 	// ErrFormIDRequired is returned when the form ID is missing.
 	ErrFormIDRequired = &EntrolyticsError{
@@ -66,12 +94,55 @@ var (
 		Message: "URL path is required",
 	}
 
+	// ErrFormSessionNotFound is returned when recording a form interaction
+	// for a session that was not started or has already ended.
+	ErrFormSessionNotFound = &EntrolyticsError{
+		Code:    "form_session_not_found",
+		Message: "form session not found; call Start first",
+	}
+
 	// Phase 2: Deployment errors
 	// ErrDeployIDRequired is returned when the deployment ID is missing.
 	ErrDeployIDRequired = &EntrolyticsError{
 		Code:    "deploy_id_required",
 		Message: "deployment ID is required",
 	}
+
+	// ErrDeploymentNotDetected is returned when no deployment information
+	// is found in the environment or build info.
+	ErrDeploymentNotDetected = &EntrolyticsError{
+		Code:    "deployment_not_detected",
+		Message: "no deployment detected in environment or build info",
+	}
+
+	// ErrClientDisabled is returned instead of sending while the client is
+	// disabled with Disable or a watched toggle. Dropped requests are
+	// counted by Client.Dropped.
+	ErrClientDisabled = &EntrolyticsError{
+		Code:    "client_disabled",
+		Message: "client is disabled",
+	}
+
+	// ErrClientRateLimited is returned instead of sending when the client's
+	// own rate limiter has no capacity for the endpoint.
+	ErrClientRateLimited = &EntrolyticsError{
+		Code:    "client_rate_limited",
+		Message: "client rate limit exceeded",
+	}
+
+	// ErrCircuitOpen is returned instead of sending while the circuit
+	// breaker is open after repeated failures.
+	ErrCircuitOpen = &EntrolyticsError{
+		Code:    "circuit_open",
+		Message: "circuit breaker is open",
+	}
+
+	// ErrNoTracker is returned when tracking through a nil Tracker, such as
+	// one from FromContext on a context without a tracker.
+	ErrNoTracker = &EntrolyticsError{
+		Code:    "no_tracker",
+		Message: "no tracker in context",
+	}
 )
 
 // EntrolyticsError represents an error from the Entrolytics SDK.
@@ -0,0 +1,269 @@
+// Package entrofiber provides Fiber middleware for Entrolytics analytics.
+//
+// Fiber is built on fasthttp rather than net/http, so the net/http
+// middleware in the entrolytics package cannot be used with it.
+//
+// Basic usage:
+//
+//	client := entrolytics.NewClient("ent_xxx")
+//
+//	app := fiber.New()
+//	app.Use(entrofiber.PageView(client, "website_id"))
+package entrofiber
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	entrolytics "github.com/entrolytics/go"
+	"github.com/gofiber/fiber/v2"
+)
+
+// trackerKey is the Fiber locals key holding the request-scoped tracker.
+const trackerKey = "entrolytics.tracker"
+
+// Options configures the Fiber page view middleware.
+//
+// MiddlewareOptions.GetUserID, GetSessionID and GetRoutePattern take an
+// *http.Request and are not used; set UserID and SessionID instead.
+type Options struct {
+	entrolytics.MiddlewareOptions
+
+	// UserID extracts the user ID from the Fiber context.
+	UserID func(c *fiber.Ctx) string
+
+	// SessionID extracts the session ID from the Fiber context.
+	SessionID func(c *fiber.Ctx) string
+}
+
+// PageView creates Fiber middleware that automatically tracks page views.
+//
+// Example:
+//
+//	app := fiber.New()
+//	app.Use(entrofiber.PageView(client, "website_id"))
+func PageView(client *entrolytics.Client, websiteID string) fiber.Handler {
+	return PageViewWithOptions(client, websiteID, Options{})
+}
+
+// PageViewWithOptions creates Fiber middleware with custom options. Route
+// templates come from c.Route().Path when UseRoutePattern is set.
+//
+// Fiber reuses request contexts once the handler returns, so every value
+// tracked is copied before tracking starts.
+//
+// Errors returned by the handler are returned unhandled to outer middleware
+// and the app's error handler, which writes the response after tracking.
+// The page view records the status fiber's default error handler sends for
+// the error, the code of a *fiber.Error or 500, even if a custom
+// ErrorHandler sends another status. The response size of failed requests
+// is not recorded.
+func PageViewWithOptions(client *entrolytics.Client, websiteID string, opts Options) fiber.Handler {
+	botClassifier := opts.BotClassifier
+	if botClassifier == nil {
+		botClassifier = entrolytics.DefaultBotClassifier()
+	}
+
+	return func(c *fiber.Ctx) error {
+		path := strings.Clone(c.Path())
+
+		tracker := entrolytics.NewTracker(client, websiteID, nil)
+		tracker.URL = path
+		tracker.Referrer = strings.Clone(c.Get(fiber.HeaderReferer))
+		tracker.UserAgent = strings.Clone(c.Get(fiber.HeaderUserAgent))
+		tracker.IPAddress = clientIP(c)
+		if opts.UserID != nil {
+			tracker.UserID = strings.Clone(opts.UserID(c))
+		}
+		if opts.SessionID != nil {
+			tracker.SessionID = strings.Clone(opts.SessionID(c))
+		}
+		c.Locals(trackerKey, tracker)
+		c.SetUserContext(entrolytics.NewContext(c.UserContext(), tracker))
+
+		// Only track GET requests to tracked paths
+		if c.Method() != fiber.MethodGet || opts.Skip(path) {
+			return c.Next()
+		}
+
+		info := entrolytics.RequestInfo{
+			Path:      path,
+			RawQuery:  string(c.Request().URI().QueryString()),
+			Referrer:  tracker.Referrer,
+			UserAgent: tracker.UserAgent,
+			IPAddress: tracker.IPAddress,
+			UserID:    tracker.UserID,
+			SessionID: tracker.SessionID,
+			Campaign:  resolveCampaign(c, &opts.MiddlewareOptions),
+		}
+		tracker.Campaign = info.Campaign
+
+		if opts.BotMode != entrolytics.BotTrack {
+			info.BotReason, _ = botClassifier.ClassifyHeaders(func(key string) string {
+				return c.Get(key)
+			})
+		}
+
+		start := time.Now()
+		err := c.Next()
+		info.Duration = time.Since(start)
+
+		resp := c.Response()
+		if err != nil {
+			// The error is returned to outer middleware and the app's error
+			// handler writes the response later, so record the status the
+			// default error handler would send. The size is not known yet.
+			info.Status = errorStatus(err)
+			info.Bytes = -1
+		} else {
+			info.Status = resp.StatusCode()
+			if n := resp.Header.ContentLength(); n >= 0 {
+				info.Bytes = int64(n)
+			} else if !resp.IsBodyStream() {
+				info.Bytes = int64(len(resp.Body()))
+			}
+		}
+
+		if route := c.Route(); route != nil {
+			info.Route = strings.Clone(route.Path)
+		}
+
+		entrolytics.TrackRequest(client, websiteID, opts.MiddlewareOptions, info)
+		return err
+	}
+}
+
+// errorStatus returns the status code for a handler error, as fiber's
+// default error handler does.
+func errorStatus(err error) int {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// TrackEvent creates Fiber middleware that tracks a custom event once the
+// handler has run. Use it on specific routes:
+//
+//	app.Post("/checkout", entrofiber.TrackEvent(client, "website_id", "checkout", nil), checkoutHandler)
+//
+// getData runs before tracking starts; values it returns must not reference
+// the Fiber context.
+func TrackEvent(client *entrolytics.Client, websiteID, eventName string, getData func(c *fiber.Ctx) map[string]interface{}) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		var data map[string]interface{}
+		if getData != nil {
+			data = getData(c)
+		}
+
+		var event entrolytics.Tracker
+		if tracker := FromContext(c); tracker != nil {
+			event = *tracker
+		} else {
+			event = *entrolytics.NewTracker(client, websiteID, nil)
+			event.URL = strings.Clone(c.Path())
+			event.Referrer = strings.Clone(c.Get(fiber.HeaderReferer))
+			event.UserAgent = strings.Clone(c.Get(fiber.HeaderUserAgent))
+			event.IPAddress = clientIP(c)
+		}
+
+		// Track event (non-blocking)
+		go func() {
+			_ = event.Track(eventName, data)
+		}()
+
+		return err
+	}
+}
+
+// FromContext returns the request-scoped tracker stored by the page view
+// middleware, or nil if the middleware is not installed. The tracker is also
+// stored in the user context, for entrolytics.FromContext(c.UserContext()).
+// It holds no reference to the Fiber context and may be used after the
+// handler returns.
+func FromContext(c *fiber.Ctx) *entrolytics.Tracker {
+	if tracker, ok := c.Locals(trackerKey).(*entrolytics.Tracker); ok {
+		return tracker
+	}
+	return nil
+}
+
+// resolveCampaign mirrors MiddlewareOptions.ResolveCampaign for Fiber.
+func resolveCampaign(c *fiber.Ctx, opts *entrolytics.MiddlewareOptions) entrolytics.Campaign {
+	if !opts.TrackCampaign {
+		return entrolytics.Campaign{}
+	}
+
+	query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+	campaign := entrolytics.ParseCampaign(query)
+	if opts.CampaignCookie == "" {
+		return campaign
+	}
+
+	stored := strings.Clone(c.Cookies(opts.CampaignCookie))
+	if campaign.IsZero() {
+		if stored != "" {
+			query, _ := url.ParseQuery(stored)
+			return entrolytics.ParseCampaign(query)
+		}
+		return campaign
+	}
+
+	// Keep the first touch if one is already stored
+	if stored == "" {
+		c.Cookie(&fiber.Cookie{
+			Name:     opts.CampaignCookie,
+			Value:    campaign.Encode(),
+			Path:     "/",
+			MaxAge:   opts.CampaignCookieMaxAge,
+			Secure:   c.Protocol() == "https",
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+	}
+	return campaign
+}
+
+// clientIP extracts the client IP address, in the same order as the
+// net/http middleware. If the app sets a ProxyHeader, Fiber's own
+// trusted-proxy handling is used instead.
+func clientIP(c *fiber.Ctx) string {
+	if c.App().Config().ProxyHeader != "" {
+		return strings.Clone(c.IP())
+	}
+
+	// Check X-Forwarded-For header first (for proxies/load balancers)
+	if xff := c.Get(fiber.HeaderXForwardedFor); xff != "" {
+		// Take the first IP if there are multiple
+		if idx := strings.Index(xff, ","); idx != -1 {
+			xff = xff[:idx]
+		}
+		return strings.Clone(strings.TrimSpace(xff))
+	}
+
+	// Check X-Real-IP header
+	if xri := c.Get("X-Real-IP"); xri != "" {
+		return strings.Clone(xri)
+	}
+
+	// Check CF-Connecting-IP (Cloudflare)
+	if cfip := c.Get("CF-Connecting-IP"); cfip != "" {
+		return strings.Clone(cfip)
+	}
+
+	// Fall back to the connection's remote address
+	if addr, ok := c.Context().RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+	host, _, err := net.SplitHostPort(c.Context().RemoteAddr().String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
@@ -0,0 +1,228 @@
+package entrolytics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// DefaultFormMaxBodyBytes is the default size up to which FormMiddleware
+// reads submissions to collect field names. The body is held in memory
+// until the handler reads it, so the default only covers typical forms
+// without file uploads.
+const DefaultFormMaxBodyBytes = 64 << 10
+
+// FormMiddlewareOptions configures the form submission middleware.
+type FormMiddlewareOptions struct {
+	// Forms maps http.ServeMux patterns, such as "POST /signup" or
+	// "/users/{id}/edit", to form IDs. Only POST requests matching a
+	// pattern are tracked. FormMiddleware panics if a pattern is invalid.
+	Forms map[string]string
+
+	// MaxBodyBytes is the size up to which submissions are buffered in
+	// memory to collect field names. Larger submissions, such as most file
+	// uploads, are tracked without field names, and at most MaxBodyBytes
+	// of them is buffered. Defaults to DefaultFormMaxBodyBytes.
+	MaxBodyBytes int64
+
+	// GetSessionID is a function to extract session ID from the request.
+	GetSessionID func(r *http.Request) string
+
+	// OnError is called when an error occurs during asynchronous tracking.
+	OnError func(err error)
+}
+
+// FormMiddleware creates HTTP middleware that tracks HTML form submissions
+// on the configured routes. For each urlencoded or multipart POST it sends
+// FieldError events reported by the handler with FormFieldError, followed
+// by a FormSubmit event with the submitted field names and count. Field
+// values are never read into events.
+//
+// A submission is successful if the handler responds with a status below
+// 400, which includes redirects, and reports no field errors.
+//
+// Example:
+//
+//	handler := entrolytics.FormMiddleware(client, "website_id", entrolytics.FormMiddlewareOptions{
+//	    Forms: map[string]string{"POST /signup": "signup"},
+//	})(mux)
+func FormMiddleware(client *Client, websiteID string, opts FormMiddlewareOptions) func(http.Handler) http.Handler {
+	maxBodyBytes := opts.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultFormMaxBodyBytes
+	}
+
+	// A ServeMux matches patterns exactly as the application's mux does
+	routes := http.NewServeMux()
+	for pattern := range opts.Forms {
+		routes.Handle(pattern, http.NotFoundHandler())
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+			_, pattern := routes.Handler(r)
+			formID, ok := opts.Forms[pattern]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fieldNames, err := readFieldNames(r, maxBodyBytes)
+			if err != nil {
+				// Not a form submission
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			collector := &fieldErrors{}
+			r = r.WithContext(context.WithValue(r.Context(), fieldErrorsKey{}, collector))
+
+			rec := NewResponseRecorder(w)
+			next.ServeHTTP(rec, r)
+
+			var sessionID string
+			if opts.GetSessionID != nil {
+				sessionID = opts.GetSessionID(r)
+			}
+			base := FormEvent{
+				WebsiteID: websiteID,
+				FormID:    formID,
+				URLPath:   r.URL.Path,
+				SessionID: sessionID,
+			}
+
+			errs := collector.list()
+			events := make([]FormEvent, 0, len(errs)+1)
+			for _, fe := range errs {
+				event := base
+				event.EventType = FieldError
+				event.FieldName = fe.field
+				event.ErrorMessage = fe.message
+				events = append(events, event)
+			}
+			submit := base
+			submit.EventType = FormSubmit
+			submit.Success = rec.StatusCode < http.StatusBadRequest && len(errs) == 0
+			submit.FieldCount = len(fieldNames)
+			submit.FieldNames = fieldNames
+			events = append(events, submit)
+
+			// The request context is canceled once the response is sent
+			ctx := context.WithoutCancel(r.Context())
+			go func() {
+				for _, event := range events {
+					if err := client.TrackFormEventWithContext(ctx, event); err != nil && opts.OnError != nil {
+						opts.OnError(err)
+					}
+				}
+			}()
+		})
+	}
+}
+
+// FormFieldError reports a validation error on a submitted field. Handlers
+// behind FormMiddleware call it to have a FieldError event sent and the
+// submission marked unsuccessful. It is a no-op for other requests.
+//
+// Example:
+//
+//	if !validEmail(r.PostFormValue("email")) {
+//	    entrolytics.FormFieldError(r, "email", "invalid email address")
+//	}
+func FormFieldError(r *http.Request, field, message string) {
+	if collector, ok := r.Context().Value(fieldErrorsKey{}).(*fieldErrors); ok {
+		collector.add(field, message)
+	}
+}
+
+type fieldErrorsKey struct{}
+
+// fieldErrors collects the validation errors reported during a request.
+type fieldErrors struct {
+	mu   sync.Mutex
+	errs []fieldError
+}
+
+type fieldError struct {
+	field   string
+	message string
+}
+
+func (c *fieldErrors) add(field, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, fieldError{field: field, message: message})
+}
+
+func (c *fieldErrors) list() []fieldError {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.errs
+}
+
+// readFieldNames buffers the request body, restores it for the handler and
+// returns the sorted names of the submitted fields. It returns nil names if
+// the body exceeds maxBodyBytes or cannot be read, and an error if the
+// request is not a form submission.
+func readFieldNames(r *http.Request, maxBodyBytes int64) ([]string, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != "application/x-www-form-urlencoded" && mediaType != "multipart/form-data" {
+		return nil, http.ErrNotSupported
+	}
+
+	if r.ContentLength > maxBodyBytes {
+		// Known to be too large, so leave the body alone
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil || int64(len(body)) > maxBodyBytes {
+		// Let the handler read the rest of the body, or see the error
+		r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return nil, nil
+	}
+	r.Body = readCloser{bytes.NewReader(body), r.Body}
+
+	seen := make(map[string]bool)
+	if mediaType == "multipart/form-data" {
+		mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if name := part.FormName(); name != "" {
+				seen[name] = true
+			}
+		}
+	} else if values, err := url.ParseQuery(string(body)); err == nil {
+		for name := range values {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readCloser reads from a replacement reader and closes the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
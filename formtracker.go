@@ -0,0 +1,321 @@
+package entrolytics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultFormAbandonTimeout is the default inactivity period after which
+// an open form session is reported as abandoned.
+const DefaultFormAbandonTimeout = 30 * time.Minute
+
+// FormTrackerOptions configures a FormTracker.
+type FormTrackerOptions struct {
+	// AbandonTimeout is the inactivity period after which a FormAbandon
+	// event is sent for an open session. Defaults to DefaultFormAbandonTimeout.
+	AbandonTimeout time.Duration
+
+	// OnError is called when an error occurs sending an automatic FormAbandon event.
+	OnError func(err error)
+}
+
+// FormTracker records form interactions per session and form, computes
+// FieldIndex, TimeOnField and TimeSinceStart, and sends each interaction
+// with TrackFormEventWithContext. Sessions left inactive for the abandon
+// timeout are reported as FormAbandon automatically. It is safe for
+// concurrent use.
+//
+// Example:
+//
+//	forms := entrolytics.NewFormTracker(client, "website_id", entrolytics.FormTrackerOptions{})
+//	defer forms.Close()
+//
+//	forms.Start(ctx, sessionID, "signup", "Sign up", "/signup")
+//	forms.Focus(ctx, sessionID, "signup", "email", "email")
+//	forms.Blur(ctx, sessionID, "signup", "email")
+//	forms.Submit(ctx, sessionID, "signup", true)
+type FormTracker struct {
+	client    *Client
+	websiteID string
+	opts      FormTrackerOptions
+
+	mu       sync.Mutex
+	sessions map[formKey]*formSession
+}
+
+type formKey struct {
+	sessionID string
+	formID    string
+}
+
+// formSession is the state of one session's interaction with a form.
+type formSession struct {
+	formName string
+	urlPath  string
+	start    time.Time
+	timer    *time.Timer
+
+	// lastActivity is when the session was last started or touched
+	lastActivity time.Time
+
+	// fields maps field names to their index, in order of first focus
+	fields map[string]int
+
+	focusedField string
+	focusedType  string
+	focusedAt    time.Time
+}
+
+// NewFormTracker creates a form tracker for a website.
+func NewFormTracker(client *Client, websiteID string, opts FormTrackerOptions) *FormTracker {
+	if opts.AbandonTimeout <= 0 {
+		opts.AbandonTimeout = DefaultFormAbandonTimeout
+	}
+	return &FormTracker{
+		client:    client,
+		websiteID: websiteID,
+		opts:      opts,
+		sessions:  make(map[formKey]*formSession),
+	}
+}
+
+// Start begins a form session and sends a FormStart event. Starting a form
+// that is already open restarts its session.
+func (t *FormTracker) Start(ctx context.Context, sessionID, formID, formName, urlPath string) error {
+	if formID == "" {
+		return ErrFormIDRequired
+	}
+	if urlPath == "" {
+		return ErrURLPathRequired
+	}
+
+	key := formKey{sessionID: sessionID, formID: formID}
+	now := time.Now()
+	s := &formSession{
+		formName:     formName,
+		urlPath:      urlPath,
+		start:        now,
+		lastActivity: now,
+		fields:       make(map[string]int),
+	}
+
+	t.mu.Lock()
+	if old, ok := t.sessions[key]; ok {
+		old.timer.Stop()
+	}
+	s.timer = time.AfterFunc(t.opts.AbandonTimeout, func() { t.expire(key, s) })
+	t.sessions[key] = s
+	event := s.event(t.websiteID, key, FormStart, now)
+	t.mu.Unlock()
+
+	return t.client.TrackFormEventWithContext(ctx, event)
+}
+
+// Focus records that a field gained focus and sends a FieldFocus event.
+// If another field is still focused, a FieldBlur event is sent for it first.
+func (t *FormTracker) Focus(ctx context.Context, sessionID, formID, fieldName, fieldType string) error {
+	key := formKey{sessionID: sessionID, formID: formID}
+	now := time.Now()
+
+	t.mu.Lock()
+	s, ok := t.touch(key)
+	if !ok {
+		t.mu.Unlock()
+		return ErrFormSessionNotFound
+	}
+	var events []FormEvent
+	if s.focusedField != "" {
+		events = append(events, s.blur(t.websiteID, key, now))
+	}
+	if _, seen := s.fields[fieldName]; !seen {
+		s.fields[fieldName] = len(s.fields) + 1
+	}
+	s.focusedField = fieldName
+	s.focusedType = fieldType
+	s.focusedAt = now
+	event := s.event(t.websiteID, key, FieldFocus, now)
+	event.FieldName = fieldName
+	event.FieldType = fieldType
+	event.FieldIndex = s.fields[fieldName]
+	events = append(events, event)
+	t.mu.Unlock()
+
+	return t.send(ctx, events...)
+}
+
+// Blur records that a field lost focus and sends a FieldBlur event with the
+// time spent on it. Blurring a field that is not focused is a no-op.
+func (t *FormTracker) Blur(ctx context.Context, sessionID, formID, fieldName string) error {
+	key := formKey{sessionID: sessionID, formID: formID}
+	now := time.Now()
+
+	t.mu.Lock()
+	s, ok := t.touch(key)
+	if !ok {
+		t.mu.Unlock()
+		return ErrFormSessionNotFound
+	}
+	if s.focusedField != fieldName {
+		t.mu.Unlock()
+		return nil
+	}
+	event := s.blur(t.websiteID, key, now)
+	t.mu.Unlock()
+
+	return t.client.TrackFormEventWithContext(ctx, event)
+}
+
+// Error records a validation error on a field and sends a FieldError event.
+func (t *FormTracker) Error(ctx context.Context, sessionID, formID, fieldName, message string) error {
+	key := formKey{sessionID: sessionID, formID: formID}
+	now := time.Now()
+
+	t.mu.Lock()
+	s, ok := t.touch(key)
+	if !ok {
+		t.mu.Unlock()
+		return ErrFormSessionNotFound
+	}
+	event := s.event(t.websiteID, key, FieldError, now)
+	event.FieldName = fieldName
+	event.FieldIndex = s.fields[fieldName]
+	event.ErrorMessage = message
+	if fieldName == s.focusedField {
+		event.FieldType = s.focusedType
+	}
+	t.mu.Unlock()
+
+	return t.client.TrackFormEventWithContext(ctx, event)
+}
+
+// Submit ends the session and sends a FormSubmit event. An unsuccessful
+// submission keeps the session open so the user can correct and resubmit.
+func (t *FormTracker) Submit(ctx context.Context, sessionID, formID string, success bool) error {
+	key := formKey{sessionID: sessionID, formID: formID}
+	now := time.Now()
+
+	t.mu.Lock()
+	s, ok := t.touch(key)
+	if !ok {
+		t.mu.Unlock()
+		return ErrFormSessionNotFound
+	}
+	event := s.event(t.websiteID, key, FormSubmit, now)
+	event.Success = success
+	if success {
+		s.timer.Stop()
+		delete(t.sessions, key)
+	}
+	t.mu.Unlock()
+
+	return t.client.TrackFormEventWithContext(ctx, event)
+}
+
+// Abandon ends the session and sends a FormAbandon event, for example when
+// the user navigates away. Sessions are also abandoned automatically after
+// the inactivity timeout.
+func (t *FormTracker) Abandon(ctx context.Context, sessionID, formID string) error {
+	key := formKey{sessionID: sessionID, formID: formID}
+
+	t.mu.Lock()
+	s, ok := t.sessions[key]
+	if !ok {
+		t.mu.Unlock()
+		return ErrFormSessionNotFound
+	}
+	s.timer.Stop()
+	delete(t.sessions, key)
+	event := s.abandon(t.websiteID, key, time.Now())
+	t.mu.Unlock()
+
+	return t.client.TrackFormEventWithContext(ctx, event)
+}
+
+// Close stops all inactivity timers. Open sessions are discarded without
+// sending FormAbandon events.
+func (t *FormTracker) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, s := range t.sessions {
+		s.timer.Stop()
+		delete(t.sessions, key)
+	}
+}
+
+// touch returns the open session for key and restarts its inactivity timer.
+// The caller must hold t.mu.
+func (t *FormTracker) touch(key formKey) (*formSession, bool) {
+	s, ok := t.sessions[key]
+	if ok {
+		s.lastActivity = time.Now()
+		s.timer.Reset(t.opts.AbandonTimeout)
+	}
+	return s, ok
+}
+
+// expire abandons s if it is still the open session for key and has been
+// inactive for the abandon timeout. A timer that fired while the session
+// was being touched finds it active, and the reset timer fires again later.
+func (t *FormTracker) expire(key formKey, s *formSession) {
+	t.mu.Lock()
+	if t.sessions[key] != s || time.Since(s.lastActivity) < t.opts.AbandonTimeout {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.sessions, key)
+	event := s.abandon(t.websiteID, key, time.Now())
+	t.mu.Unlock()
+
+	if err := t.client.TrackFormEventWithContext(context.Background(), event); err != nil && t.opts.OnError != nil {
+		t.opts.OnError(err)
+	}
+}
+
+// send sends events in order, stopping at the first error.
+func (t *FormTracker) send(ctx context.Context, events ...FormEvent) error {
+	for _, event := range events {
+		if err := t.client.TrackFormEventWithContext(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// event returns a form event of the given type with the session's timing.
+func (s *formSession) event(websiteID string, key formKey, eventType FormEventType, now time.Time) FormEvent {
+	return FormEvent{
+		WebsiteID:      websiteID,
+		EventType:      eventType,
+		FormID:         key.formID,
+		FormName:       s.formName,
+		URLPath:        s.urlPath,
+		TimeSinceStart: int(now.Sub(s.start).Milliseconds()),
+		SessionID:      key.sessionID,
+		Timestamp:      now,
+	}
+}
+
+// blur returns a FieldBlur event for the focused field and clears it.
+func (s *formSession) blur(websiteID string, key formKey, now time.Time) FormEvent {
+	event := s.event(websiteID, key, FieldBlur, now)
+	event.FieldName = s.focusedField
+	event.FieldType = s.focusedType
+	event.FieldIndex = s.fields[s.focusedField]
+	event.TimeOnField = int(now.Sub(s.focusedAt).Milliseconds())
+	s.focusedField = ""
+	s.focusedType = ""
+	return event
+}
+
+// abandon returns a FormAbandon event naming the last focused field, if any.
+func (s *formSession) abandon(websiteID string, key formKey, now time.Time) FormEvent {
+	event := s.event(websiteID, key, FormAbandon, now)
+	if s.focusedField != "" {
+		event.FieldName = s.focusedField
+		event.FieldType = s.focusedType
+		event.FieldIndex = s.fields[s.focusedField]
+	}
+	return event
+}
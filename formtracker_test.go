@@ -0,0 +1,32 @@
+package entrolytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormTrackerExpireIgnoresActiveSession(t *testing.T) {
+	client, bodies := newTestClient(t, ClientOptions{})
+	tracker := NewFormTracker(client, "site", FormTrackerOptions{AbandonTimeout: time.Hour})
+	defer tracker.Close()
+
+	ctx := context.Background()
+	require.NoError(t, tracker.Start(ctx, "sess", "signup", "Sign up", "/signup"))
+	key := formKey{sessionID: "sess", formID: "signup"}
+	s := tracker.sessions[key]
+
+	// A timer that fired just as the session was touched
+	tracker.expire(key, s)
+	assert.Same(t, s, tracker.sessions[key])
+	assert.Len(t, *bodies, 1)
+
+	s.lastActivity = time.Now().Add(-time.Hour)
+	tracker.expire(key, s)
+	assert.NotContains(t, tracker.sessions, key)
+	require.Len(t, *bodies, 2)
+	assert.Contains(t, string((*bodies)[1]), string(FormAbandon))
+}
@@ -0,0 +1,168 @@
+// Package entrogin provides Gin middleware for Entrolytics analytics.
+//
+// Basic usage:
+//
+//	client := entrolytics.NewClient("ent_xxx")
+//
+//	r := gin.Default()
+//	r.Use(entrogin.PageView(client, "website_id"))
+//
+//	r.POST("/signup", func(c *gin.Context) {
+//	    _ = entrogin.FromContext(c).Track("signup", nil)
+//	})
+package entrogin
+
+import (
+	"net/http"
+	"time"
+
+	entrolytics "github.com/entrolytics/go"
+	"github.com/gin-gonic/gin"
+)
+
+// trackerKey is the Gin context key holding the request-scoped tracker.
+const trackerKey = "entrolytics.tracker"
+
+// Options configures the Gin page view middleware.
+type Options struct {
+	entrolytics.MiddlewareOptions
+
+	// UserID extracts the user ID from the Gin context.
+	// Takes precedence over MiddlewareOptions.GetUserID.
+	UserID func(c *gin.Context) string
+
+	// SessionID extracts the session ID from the Gin context.
+	// Takes precedence over MiddlewareOptions.GetSessionID.
+	SessionID func(c *gin.Context) string
+}
+
+// PageView creates Gin middleware that automatically tracks page views.
+//
+// Example:
+//
+//	r := gin.Default()
+//	r.Use(entrogin.PageView(client, "website_id"))
+func PageView(client *entrolytics.Client, websiteID string) gin.HandlerFunc {
+	return PageViewWithOptions(client, websiteID, Options{})
+}
+
+// PageViewWithOptions creates Gin middleware with custom options. Route
+// templates come from c.FullPath() when UseRoutePattern is set, and the
+// client IP from c.ClientIP(), which honors the engine's trusted proxies.
+func PageViewWithOptions(client *entrolytics.Client, websiteID string, opts Options) gin.HandlerFunc {
+	botClassifier := opts.BotClassifier
+	if botClassifier == nil {
+		botClassifier = entrolytics.DefaultBotClassifier()
+	}
+
+	return func(c *gin.Context) {
+		r := c.Request
+
+		tracker := entrolytics.NewTracker(client, websiteID, r)
+		tracker.IPAddress = c.ClientIP()
+		tracker.UserID = userID(c, &opts)
+		tracker.SessionID = sessionID(c, &opts)
+		c.Set(trackerKey, tracker)
+		c.Request = r.WithContext(entrolytics.NewContext(r.Context(), tracker))
+		r = c.Request
+
+		// Only track GET requests to tracked paths
+		if r.Method != http.MethodGet || opts.Skip(r.URL.Path) {
+			c.Next()
+			return
+		}
+
+		info := entrolytics.RequestInfo{
+			Path:      r.URL.Path,
+			RawQuery:  r.URL.RawQuery,
+			Referrer:  tracker.Referrer,
+			UserAgent: tracker.UserAgent,
+			IPAddress: tracker.IPAddress,
+			UserID:    tracker.UserID,
+			SessionID: tracker.SessionID,
+			Campaign:  opts.ResolveCampaign(c.Writer, r),
+		}
+		tracker.Campaign = info.Campaign
+
+		if opts.BotMode != entrolytics.BotTrack {
+			info.BotReason, _ = botClassifier.Classify(r)
+		}
+
+		start := time.Now()
+		c.Next()
+		info.Duration = time.Since(start)
+
+		info.Status = c.Writer.Status()
+		if size := c.Writer.Size(); size > 0 {
+			info.Bytes = int64(size)
+		}
+
+		info.Route = c.FullPath()
+		if opts.GetRoutePattern != nil {
+			if route := opts.GetRoutePattern(r); route != "" {
+				info.Route = route
+			}
+		}
+
+		entrolytics.TrackRequest(client, websiteID, opts.MiddlewareOptions, info)
+	}
+}
+
+// TrackEvent creates Gin middleware that tracks a custom event once the
+// handler has run. Use it on specific routes:
+//
+//	r.POST("/checkout", entrogin.TrackEvent(client, "website_id", "checkout", nil), checkoutHandler)
+func TrackEvent(client *entrolytics.Client, websiteID, eventName string, getData func(c *gin.Context) map[string]interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		var data map[string]interface{}
+		if getData != nil {
+			data = getData(c)
+		}
+
+		tracker := FromContext(c)
+		if tracker == nil {
+			tracker = entrolytics.NewTracker(client, websiteID, c.Request)
+			tracker.IPAddress = c.ClientIP()
+		}
+		event := *tracker
+
+		// Track event (non-blocking)
+		go func() {
+			_ = event.Track(eventName, data)
+		}()
+	}
+}
+
+// FromContext returns the request-scoped tracker stored by the page view
+// middleware, or nil if the middleware is not installed. The tracker is also
+// stored in the request context, for entrolytics.FromContext(c.Request.Context()).
+func FromContext(c *gin.Context) *entrolytics.Tracker {
+	if v, ok := c.Get(trackerKey); ok {
+		if tracker, ok := v.(*entrolytics.Tracker); ok {
+			return tracker
+		}
+	}
+	return nil
+}
+
+func userID(c *gin.Context, opts *Options) string {
+	if opts.UserID != nil {
+		return opts.UserID(c)
+	}
+	if opts.GetUserID != nil {
+		return opts.GetUserID(c.Request)
+	}
+	return ""
+}
+
+func sessionID(c *gin.Context, opts *Options) string {
+	if opts.SessionID != nil {
+		return opts.SessionID(c)
+	}
+	if opts.GetSessionID != nil {
+		return opts.GetSessionID(c.Request)
+	}
+	return ""
+}
@@ -0,0 +1,17 @@
+module github.com/entrolytics/go/grpc
+
+go 1.25.0
+
+require (
+	github.com/entrolytics/go v0.0.0-20261018160605-ef9101fdda2e
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,198 @@
+// Package entrogrpc provides gRPC server interceptors for Entrolytics analytics.
+//
+// Each RPC is tracked as an event with the full method name, status code,
+// latency and peer IP address.
+//
+// Basic usage:
+//
+//	client := entrolytics.NewClient("ent_xxx")
+//
+//	server := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(entrogrpc.UnaryServerInterceptor(client, "website_id", entrogrpc.Options{})),
+//	    grpc.ChainStreamInterceptor(entrogrpc.StreamServerInterceptor(client, "website_id", entrogrpc.Options{})),
+//	)
+package entrogrpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	entrolytics "github.com/entrolytics/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RPCEventName is the default event name for tracked RPCs.
+const RPCEventName = "$rpc"
+
+// DefaultSkipMethods are the health checking and reflection services,
+// which are not tracked unless Options.SkipMethods is set.
+var DefaultSkipMethods = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+// Options configures the gRPC interceptors.
+type Options struct {
+	// EventName is the event name for tracked RPCs. Defaults to RPCEventName.
+	EventName string
+
+	// SkipMethods are full method name prefixes, such as "/pkg.Service/" or
+	// "/pkg.Service/Method", that should not be tracked.
+	// Defaults to DefaultSkipMethods if empty.
+	SkipMethods []string
+
+	// UserIDMetadataKey is the incoming metadata key holding the user ID.
+	UserIDMetadataKey string
+
+	// SessionIDMetadataKey is the incoming metadata key holding the session ID.
+	SessionIDMetadataKey string
+
+	// GetUserID is a function to extract user ID from the RPC context.
+	// Takes precedence over UserIDMetadataKey.
+	GetUserID func(ctx context.Context) string
+
+	// GetSessionID is a function to extract session ID from the RPC context.
+	// Takes precedence over SessionIDMetadataKey.
+	GetSessionID func(ctx context.Context) string
+
+	// OnError is called when an error occurs during asynchronous tracking.
+	OnError func(err error)
+}
+
+// UnaryServerInterceptor creates an interceptor that tracks an event per unary RPC.
+func UnaryServerInterceptor(client *entrolytics.Client, websiteID string, opts Options) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if opts.skip(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		track(ctx, client, websiteID, &opts, info.FullMethod, false, time.Since(start), err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor creates an interceptor that tracks an event per
+// streaming RPC, once the stream completes.
+func StreamServerInterceptor(client *entrolytics.Client, websiteID string, opts Options) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if opts.skip(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		start := time.Now()
+		err := handler(srv, ss)
+		track(ss.Context(), client, websiteID, &opts, info.FullMethod, true, time.Since(start), err)
+		return err
+	}
+}
+
+func (opts *Options) skip(fullMethod string) bool {
+	skipMethods := opts.SkipMethods
+	if len(skipMethods) == 0 {
+		skipMethods = DefaultSkipMethods
+	}
+	for _, prefix := range skipMethods {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// track sends the RPC event (non-blocking).
+func track(ctx context.Context, client *entrolytics.Client, websiteID string, opts *Options, fullMethod string, stream bool, duration time.Duration, rpcErr error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	service, method := splitMethod(fullMethod)
+	code := status.Code(rpcErr)
+
+	eventName := opts.EventName
+	if eventName == "" {
+		eventName = RPCEventName
+	}
+
+	event := entrolytics.Event{
+		WebsiteID: websiteID,
+		Name:      eventName,
+		Data: map[string]interface{}{
+			"service":     service,
+			"method":      method,
+			"code":        code.String(),
+			"stream":      stream,
+			"duration_ms": duration.Milliseconds(),
+		},
+		URL:       fullMethod,
+		UserAgent: firstValue(md, "user-agent"),
+		IPAddress: peerIP(ctx, md),
+	}
+
+	if opts.GetUserID != nil {
+		event.UserID = opts.GetUserID(ctx)
+	} else if opts.UserIDMetadataKey != "" {
+		event.UserID = firstValue(md, opts.UserIDMetadataKey)
+	}
+	if opts.GetSessionID != nil {
+		event.SessionID = opts.GetSessionID(ctx)
+	} else if opts.SessionIDMetadataKey != "" {
+		event.SessionID = firstValue(md, opts.SessionIDMetadataKey)
+	}
+
+	// The RPC context is canceled once the handler returns
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := client.TrackWithContext(ctx, event); err != nil && opts.OnError != nil {
+			opts.OnError(err)
+		}
+	}()
+}
+
+// splitMethod splits "/pkg.Service/Method" into service and method names.
+func splitMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if idx := strings.LastIndex(fullMethod, "/"); idx != -1 {
+		return fullMethod[:idx], fullMethod[idx+1:]
+	}
+	return "", fullMethod
+}
+
+// peerIP returns the client IP, preferring proxy headers forwarded as metadata.
+func peerIP(ctx context.Context, md metadata.MD) string {
+	if xff := firstValue(md, "x-forwarded-for"); xff != "" {
+		// Take the first IP if there are multiple
+		if idx := strings.Index(xff, ","); idx != -1 {
+			return strings.TrimSpace(xff[:idx])
+		}
+		return strings.TrimSpace(xff)
+	}
+	if xri := firstValue(md, "x-real-ip"); xri != "" {
+		return xri
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if addr, ok := p.Addr.(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+package entrolytics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultToggleInterval is the default interval at which WatchEnabled polls its source.
+const DefaultToggleInterval = 30 * time.Second
+
+// Enable resumes sending after Disable.
+func (c *Client) Enable() {
+	c.enabled.Store(true)
+}
+
+// Disable stops the client from sending, for example during an incident.
+// While disabled, every tracking call returns ErrClientDisabled without a
+// network request and is counted by Dropped. It is safe for concurrent use.
+func (c *Client) Disable() {
+	c.enabled.Store(false)
+}
+
+// Enabled reports whether the client is sending.
+func (c *Client) Enabled() bool {
+	return c.enabled.Load()
+}
+
+// Dropped returns the number of requests dropped while the client was disabled.
+func (c *Client) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// ToggleSource reports whether tracking should be enabled.
+type ToggleSource interface {
+	Enabled(ctx context.Context) (bool, error)
+}
+
+// ToggleFunc adapts a function to a ToggleSource.
+type ToggleFunc func(ctx context.Context) (bool, error)
+
+// Enabled calls f(ctx).
+func (f ToggleFunc) Enabled(ctx context.Context) (bool, error) {
+	return f(ctx)
+}
+
+// FileToggle is a ToggleSource read from a file containing a boolean such
+// as "true", "false", "1" or "0". A missing file means enabled, so writing
+// "false" to the file disables tracking and removing it re-enables it.
+type FileToggle string
+
+// Enabled reads the file.
+func (path FileToggle) Enabled(context.Context) (bool, error) {
+	data, err := os.ReadFile(string(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return parseToggle(data)
+}
+
+// HTTPToggle is a ToggleSource fetched from a URL that responds 200 OK with
+// a boolean such as "true" or "false", or a JSON object like {"enabled": false}.
+type HTTPToggle struct {
+	// URL is fetched with a GET request (required).
+	URL string
+
+	// Client is the HTTP client used. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Enabled fetches the URL.
+func (t HTTPToggle) Enabled(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("entrolytics: toggle %s: unexpected status %d", t.URL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if err != nil {
+		return false, err
+	}
+	return parseToggle(data)
+}
+
+// ToggleOptions configures WatchEnabled.
+type ToggleOptions struct {
+	// Interval between checks of the source. Defaults to DefaultToggleInterval.
+	Interval time.Duration
+
+	// OnError is called when the source cannot be read. The client keeps
+	// its current state.
+	OnError func(err error)
+}
+
+// WatchEnabled checks source immediately and then every interval in the
+// background, enabling or disabling the client to match, until ctx is
+// canceled.
+//
+// Example:
+//
+//	client.WatchEnabled(ctx, entrolytics.FileToggle("/etc/entrolytics/enabled"), entrolytics.ToggleOptions{})
+func (c *Client) WatchEnabled(ctx context.Context, source ToggleSource, opts ToggleOptions) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultToggleInterval
+	}
+
+	check := func() {
+		enabled, err := source.Enabled(ctx)
+		if err != nil {
+			if opts.OnError != nil && ctx.Err() == nil {
+				opts.OnError(err)
+			}
+			return
+		}
+		if enabled != c.Enabled() {
+			c.enabled.Store(enabled)
+			c.logf("tracking enabled: %t", enabled)
+		}
+	}
+
+	go func() {
+		check()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				check()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// parseToggle parses a boolean or a JSON object with an "enabled" field.
+func parseToggle(data []byte) (bool, error) {
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, "{") {
+		var v struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return false, err
+		}
+		if v.Enabled == nil {
+			return false, errors.New(`entrolytics: toggle has no "enabled" field`)
+		}
+		return *v.Enabled, nil
+	}
+	return strconv.ParseBool(text)
+}
@@ -0,0 +1,56 @@
+package entrolytics
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisableSkipsBeforeValidation(t *testing.T) {
+	plan := &TrackingPlan{Events: map[string]*PropertySchema{
+		"signup": {Properties: map[string]*PropertySchema{"plan": {Type: "string"}}},
+	}}
+	client, bodies := newTestClient(t, ClientOptions{WebsiteID: "site", TrackingPlan: plan})
+	client.Disable()
+
+	ctx := context.Background()
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{"Track", func() error {
+			return client.TrackWithContext(ctx, Event{Name: "signup", Data: map[string]interface{}{"plan": 1}})
+		}},
+		{"Track invalid data", func() error {
+			return client.TrackWithContext(ctx, Event{Name: "signup", Data: map[string]interface{}{"n": math.NaN()}})
+		}},
+		{"PageView", func() error { return client.PageViewWithContext(ctx, PageView{}) }},
+		{"Identify", func() error { return client.IdentifyWithContext(ctx, Identify{}) }},
+		{"TrackVital", func() error { return client.TrackVitalWithContext(ctx, WebVital{}) }},
+		{"TrackFormEvent", func() error { return client.TrackFormEventWithContext(ctx, FormEvent{}) }},
+		{"SetDeployment", func() error { return client.SetDeploymentWithContext(ctx, Deployment{}) }},
+	}
+	for _, c := range calls {
+		assert.Equal(t, ErrClientDisabled, c.call(), c.name)
+	}
+	assert.Equal(t, uint64(len(calls)), client.Dropped())
+	assert.Empty(t, *bodies)
+
+	client.Enable()
+	require.NoError(t, client.Track(Event{Name: "signup", Data: map[string]interface{}{"plan": "pro"}}))
+	assert.Len(t, *bodies, 1)
+}
+
+func TestDisabledOptionValidatesWithoutSending(t *testing.T) {
+	client, bodies := newTestClient(t, ClientOptions{Disabled: true})
+
+	assert.Equal(t, ErrWebsiteIDRequired, client.Track(Event{Name: "signup"}))
+	assert.Equal(t, ErrEventNameRequired, client.Track(Event{WebsiteID: "site"}))
+	assert.Equal(t, ErrWebsiteIDRequired, client.PageView(PageView{URL: "/"}))
+	assert.NoError(t, client.Track(Event{WebsiteID: "site", Name: "signup"}))
+	assert.Empty(t, *bodies)
+	assert.Equal(t, uint64(0), client.Dropped())
+}
@@ -3,6 +3,7 @@ package entrolytics
 import (
 	"net/http"
 	"strings"
+	"time"
 )
 
 // PageViewMiddleware creates HTTP middleware that automatically tracks page views.
@@ -42,6 +43,73 @@ type MiddlewareOptions struct {
 
 	// OnError is called when an error occurs during asynchronous tracking.
 	OnError func(err error)
+
+	// BotMode controls how requests classified as bots are tracked.
+	// Defaults to BotTrack, which tracks them like any other request.
+	BotMode BotMode
+
+	// BotClassifier classifies requests as bots. Defaults to DefaultBotClassifier().
+	BotClassifier *BotClassifier
+
+	// BotEventName is the event name used with BotSeparateEvent.
+	// Defaults to DefaultBotEventName.
+	BotEventName string
+
+	// UseRoutePattern reports the matched route template (e.g. "/users/{id}")
+	// instead of the request path. The template comes from GetRoutePattern if
+	// set, falling back to RoutePattern for http.ServeMux routes.
+	UseRoutePattern bool
+
+	// GetRoutePattern extracts the route template for routers other than
+	// http.ServeMux. It is called after the handler has run, once routing is
+	// complete.
+	//
+	// Example with chi:
+	//
+	//	GetRoutePattern: func(r *http.Request) string {
+	//	    return chi.RouteContext(r.Context()).RoutePattern()
+	//	}
+	//
+	// Example with gorilla/mux (register the middleware with router.Use):
+	//
+	//	GetRoutePattern: func(r *http.Request) string {
+	//	    if route := mux.CurrentRoute(r); route != nil {
+	//	        tpl, _ := route.GetPathTemplate()
+	//	        return tpl
+	//	    }
+	//	    return ""
+	//	}
+	GetRoutePattern func(r *http.Request) string
+
+	// PathNormalizer rewrites request paths that have no route template,
+	// e.g. to replace IDs and UUIDs with placeholders. See NewPathNormalizer.
+	PathNormalizer *PathNormalizer
+
+	// IncludeRawPath adds the original request path to the page view Data as
+	// "raw_path" when the reported URL differs from it.
+	IncludeRawPath bool
+
+	// TrackCampaign attaches UTM parameters and ad click IDs from the request
+	// URL to page views, independently of TrackQueryParams.
+	TrackCampaign bool
+
+	// CampaignCookie, if set, names a cookie that persists first-touch
+	// campaign attribution. Requests without campaign parameters are
+	// attributed to the stored campaign. Requires TrackCampaign.
+	CampaignCookie string
+
+	// CampaignCookieMaxAge is the campaign cookie lifetime in seconds.
+	// Defaults to 0, which keeps the cookie for the browser session.
+	CampaignCookieMaxAge int
+
+	// RecordResponse adds the response status ("status"), handler latency in
+	// milliseconds ("duration_ms") and response size in bytes ("bytes"),
+	// when known, to the page view Data.
+	RecordResponse bool
+
+	// TrackStatus, if set, only tracks responses whose status code it
+	// accepts. Use SuccessStatus to track 2xx responses only.
+	TrackStatus func(status int) bool
 }
 
 var defaultSkipExtensions = []string{
@@ -51,70 +119,215 @@ var defaultSkipExtensions = []string{
 
 // PageViewMiddlewareWithOptions creates HTTP middleware with custom options.
 func PageViewMiddlewareWithOptions(client *Client, websiteID string, opts MiddlewareOptions) func(http.Handler) http.Handler {
-	skipExtensions := opts.SkipExtensions
-	if len(skipExtensions) == 0 {
-		skipExtensions = defaultSkipExtensions
+	botClassifier := opts.BotClassifier
+	if botClassifier == nil {
+		botClassifier = DefaultBotClassifier()
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Only track GET requests
-			if r.Method != http.MethodGet {
+			// Only track GET requests to tracked paths
+			if r.Method != http.MethodGet || opts.Skip(r.URL.Path) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Check skip paths
-			for _, path := range opts.SkipPaths {
-				if strings.HasPrefix(r.URL.Path, path) {
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
-
-			// Check skip extensions
-			for _, ext := range skipExtensions {
-				if strings.HasSuffix(r.URL.Path, ext) {
-					next.ServeHTTP(w, r)
-					return
+			// Classify bots
+			var botReason string
+			if opts.BotMode != BotTrack {
+				if reason, isBot := botClassifier.Classify(r); isBot {
+					if opts.BotMode == BotSkip {
+						next.ServeHTTP(w, r)
+						return
+					}
+					botReason = reason
 				}
 			}
 
-			// Build URL
-			url := r.URL.Path
-			if opts.TrackQueryParams && r.URL.RawQuery != "" {
-				url = url + "?" + r.URL.RawQuery
+			info := RequestInfo{
+				Path:      r.URL.Path,
+				RawQuery:  r.URL.RawQuery,
+				Referrer:  r.Referer(),
+				UserAgent: r.UserAgent(),
+				IPAddress: getClientIP(r),
+				BotReason: botReason,
+				// Resolve campaign attribution before the handler writes headers
+				Campaign: opts.ResolveCampaign(w, r),
 			}
 
 			// Extract user info
-			var userID, sessionID string
 			if opts.GetUserID != nil {
-				userID = opts.GetUserID(r)
+				info.UserID = opts.GetUserID(r)
 			}
 			if opts.GetSessionID != nil {
-				sessionID = opts.GetSessionID(r)
+				info.SessionID = opts.GetSessionID(r)
 			}
 
-			// Track page view (non-blocking)
-			go func() {
-				if err := client.PageView(PageView{
-					WebsiteID: websiteID,
-					URL:       url,
-					Referrer:  r.Referer(),
-					UserAgent: r.UserAgent(),
-					IPAddress: getClientIP(r),
-					UserID:    userID,
-					SessionID: sessionID,
-				}); err != nil && opts.OnError != nil {
-					opts.OnError(err)
-				}
-			}()
+			// Record the response only when needed, to keep the fast path unwrapped
+			var rr *ResponseRecorder
+			if opts.RecordResponse || opts.TrackStatus != nil {
+				rr = NewResponseRecorder(w)
+				w = rr
+			}
 
+			start := time.Now()
 			next.ServeHTTP(w, r)
+			info.Duration = time.Since(start)
+
+			if rr != nil {
+				info.Status = rr.StatusCode
+				info.Bytes = rr.BytesWritten
+			}
+
+			// Routing is complete, so route templates are known
+			info.Route = opts.route(r)
+
+			TrackRequest(client, websiteID, opts, info)
 		})
 	}
 }
 
+// Skip reports whether requests to path are excluded by SkipPaths or SkipExtensions.
+func (opts *MiddlewareOptions) Skip(path string) bool {
+	for _, prefix := range opts.SkipPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	skipExtensions := opts.SkipExtensions
+	if len(skipExtensions) == 0 {
+		skipExtensions = defaultSkipExtensions
+	}
+	for _, ext := range skipExtensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestInfo describes a completed request for page view tracking.
+// Framework adapters that cannot use the net/http middleware fill it in
+// and pass it to TrackRequest.
+type RequestInfo struct {
+	// Route is the matched route template, if known.
+	Route string
+
+	// Path is the request path.
+	Path string
+
+	// RawQuery is the encoded query string, without the leading '?'.
+	RawQuery string
+
+	// Referrer is the referrer URL.
+	Referrer string
+
+	// UserAgent is the client's user agent string.
+	UserAgent string
+
+	// IPAddress is the client's IP address.
+	IPAddress string
+
+	// UserID identifies a logged-in user.
+	UserID string
+
+	// SessionID identifies the user session.
+	SessionID string
+
+	// Campaign is the marketing attribution for the request.
+	Campaign Campaign
+
+	// BotReason is the bot classification reason, or empty if the request
+	// was not classified as a bot.
+	BotReason string
+
+	// Status is the response status code.
+	Status int
+
+	// Duration is the handler latency.
+	Duration time.Duration
+
+	// Bytes is the response body size, or negative if it is not known.
+	Bytes int64
+}
+
+// TrackRequest tracks a completed request as a page view, applying the
+// bot, path, status and response options in opts. Tracking is non-blocking;
+// errors are passed to opts.OnError.
+func TrackRequest(client *Client, websiteID string, opts MiddlewareOptions, info RequestInfo) {
+	if info.BotReason != "" && opts.BotMode == BotSkip {
+		return
+	}
+	if opts.TrackStatus != nil && !opts.TrackStatus(info.Status) {
+		return
+	}
+
+	path := opts.reportedPath(info.Route, info.Path)
+	url := path
+	if opts.TrackQueryParams && info.RawQuery != "" {
+		url = url + "?" + info.RawQuery
+	}
+
+	data := make(map[string]interface{})
+	if info.BotReason != "" {
+		data["bot_reason"] = info.BotReason
+	}
+	if opts.IncludeRawPath && path != info.Path {
+		data["raw_path"] = info.Path
+	}
+	if opts.RecordResponse {
+		data["status"] = info.Status
+		data["duration_ms"] = info.Duration.Milliseconds()
+		if info.Bytes >= 0 {
+			data["bytes"] = info.Bytes
+		}
+	}
+
+	pv := PageView{
+		WebsiteID: websiteID,
+		URL:       url,
+		Referrer:  info.Referrer,
+		UserAgent: info.UserAgent,
+		IPAddress: info.IPAddress,
+		UserID:    info.UserID,
+		SessionID: info.SessionID,
+		Campaign:  info.Campaign,
+	}
+
+	// Track page view (non-blocking)
+	go func() {
+		var err error
+		if info.BotReason != "" && opts.BotMode == BotSeparateEvent {
+			botEventName := opts.BotEventName
+			if botEventName == "" {
+				botEventName = DefaultBotEventName
+			}
+			err = client.Track(Event{
+				WebsiteID: pv.WebsiteID,
+				Name:      botEventName,
+				Data:      data,
+				URL:       pv.URL,
+				Referrer:  pv.Referrer,
+				UserID:    pv.UserID,
+				SessionID: pv.SessionID,
+				UserAgent: pv.UserAgent,
+				IPAddress: pv.IPAddress,
+				Campaign:  pv.Campaign,
+			})
+		} else {
+			if info.BotReason != "" {
+				data["bot"] = true
+			}
+			pv.Data = data
+			err = client.PageView(pv)
+		}
+		if err != nil && opts.OnError != nil {
+			opts.OnError(err)
+		}
+	}()
+}
+
 // TrackEventHandler wraps an http.HandlerFunc to track events.
 // Use this for specific endpoints where you want to track custom events.
 //
@@ -145,49 +358,18 @@ func TrackEventHandler(client *Client, websiteID, eventName string, getData func
 	}
 }
 
-// ResponseRecorder wraps http.ResponseWriter to capture the status code.
-type ResponseRecorder struct {
-	http.ResponseWriter
-	StatusCode int
-}
-
-// WriteHeader captures the status code.
-func (rr *ResponseRecorder) WriteHeader(code int) {
-	rr.StatusCode = code
-	rr.ResponseWriter.WriteHeader(code)
-}
-
 // TrackOnSuccess creates middleware that only tracks page views on successful responses (2xx).
+//
+// Deprecated: Use PageViewMiddlewareWithOptions with TrackStatus set to SuccessStatus.
 func TrackOnSuccess(client *Client, websiteID string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Only track GET requests
-			if r.Method != http.MethodGet {
-				next.ServeHTTP(w, r)
-				return
-			}
+	return PageViewMiddlewareWithOptions(client, websiteID, MiddlewareOptions{
+		TrackStatus: SuccessStatus,
+	})
+}
 
-			rr := &ResponseRecorder{ResponseWriter: w, StatusCode: http.StatusOK}
-			next.ServeHTTP(rr, r)
-
-			// Only track successful responses
-			if rr.StatusCode >= 200 && rr.StatusCode < 300 {
-				go func() {
-					if err := client.PageView(PageView{
-						WebsiteID: websiteID,
-						URL:       r.URL.Path,
-						Referrer:  r.Referer(),
-						UserAgent: r.UserAgent(),
-						IPAddress: getClientIP(r),
-					}); err != nil {
-						// Note: TrackOnSuccess doesn't accept options, so we can't use OnError here easily without changing signature.
-						// Leaving as is for now or maybe log to debug logger if we add one.
-						// Actually, for consistency let's just ignore widely but the main middleware is fixed.
-					}
-				}()
-			}
-		})
-	}
+// SuccessStatus reports whether status is a successful (2xx) response.
+func SuccessStatus(status int) bool {
+	return status >= 200 && status < 300
 }
 
 // getClientIP extracts the client IP address from the request.
@@ -0,0 +1,287 @@
+package entrolytics
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DataLimits bounds the size of Data, Traits and Attribution maps. Data
+// exceeding a limit is rejected with a *DataValidationError, not truncated.
+// Zero or negative fields apply no limit, so the zero value only checks
+// that data can be sent as JSON.
+type DataLimits struct {
+	// MaxKeys is the maximum number of keys in each object.
+	MaxKeys int
+
+	// MaxDepth is the maximum nesting depth, where the map itself is depth 1.
+	MaxDepth int
+
+	// MaxStringLength is the maximum length of a string value, in bytes.
+	MaxStringLength int
+}
+
+// RecommendedDataLimits are limits suited to most analytics data. They are
+// not applied unless set in ClientOptions.DataLimits.
+var RecommendedDataLimits = DataLimits{
+	MaxKeys:         100,
+	MaxDepth:        10,
+	MaxStringLength: 8 << 10,
+}
+
+// DataValidationError is returned when a Data, Traits or Attribution value
+// cannot be sent.
+type DataValidationError struct {
+	// Path locates the offending value, e.g. "data.items[2].price".
+	Path string
+
+	// Message describes the problem.
+	Message string
+}
+
+func (e *DataValidationError) Error() string {
+	return fmt.Sprintf("entrolytics: invalid %s: %s", e.Path, e.Message)
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonNumberType    = reflect.TypeOf(json.Number(""))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// NormalizeData checks that data can be sent as JSON within limits and
+// returns a copy made only of JSON types. time.Time values become RFC 3339
+// strings; json.Marshaler, encoding.TextMarshaler, error and fmt.Stringer
+// values are converted the way they describe themselves; structs are
+// converted as by encoding/json. Channels, functions, complex numbers, NaN
+// and infinite floats, and cyclic structures are rejected with a
+// *DataValidationError naming the path, which starts with name.
+func NormalizeData(name string, data map[string]interface{}, limits DataLimits) (map[string]interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	n := &normalizer{limits: limits, visiting: make(map[uintptr]bool)}
+	v, err := n.normalize(name, reflect.ValueOf(data), 1)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+type normalizer struct {
+	limits DataLimits
+
+	// visiting holds the maps, slices and pointers on the current path
+	visiting map[uintptr]bool
+}
+
+func (n *normalizer) fail(path, format string, args ...interface{}) error {
+	return &DataValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+func (n *normalizer) normalize(path string, rv reflect.Value, depth int) (interface{}, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	// Self-describing types, checked before the underlying kind
+	switch t := rv.Type(); {
+	case t == timeType:
+		return rv.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	case t == jsonNumberType:
+		return n.number(path, rv.Interface().(json.Number))
+	case t.Implements(jsonMarshalerType):
+		if isNilValue(rv) {
+			return nil, nil
+		}
+		raw, err := rv.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, n.fail(path, "%v", err)
+		}
+		return n.decode(path, raw, depth)
+	case t.Implements(textMarshalerType):
+		if isNilValue(rv) {
+			return nil, nil
+		}
+		text, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, n.fail(path, "%v", err)
+		}
+		return n.string(path, string(text))
+	case t.Implements(errorType):
+		if isNilValue(rv) {
+			return nil, nil
+		}
+		return n.string(path, rv.Interface().(error).Error())
+	case t.Implements(stringerType):
+		if isNilValue(rv) {
+			return nil, nil
+		}
+		return n.string(path, rv.Interface().(fmt.Stringer).String())
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return n.string(path, rv.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, n.fail(path, "%v is not a JSON number", f)
+		}
+		return f, nil
+
+	case reflect.Interface:
+		return n.normalize(path, rv.Elem(), depth)
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if err := n.enter(path, rv.Pointer()); err != nil {
+			return nil, err
+		}
+		defer delete(n.visiting, rv.Pointer())
+		return n.normalize(path, rv.Elem(), depth)
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, n.fail(path, "map keys must be strings, got %s", rv.Type().Key())
+		}
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return n.object(path, rv, depth)
+
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Sent base64-encoded, as by encoding/json
+			return rv.Bytes(), nil
+		}
+		if err := n.enter(path, rv.Pointer()); err != nil {
+			return nil, err
+		}
+		defer delete(n.visiting, rv.Pointer())
+		return n.array(path, rv, depth)
+	case reflect.Array:
+		return n.array(path, rv, depth)
+
+	case reflect.Struct:
+		raw, err := json.Marshal(rv.Interface())
+		if err != nil {
+			return nil, n.fail(path, "%v", err)
+		}
+		return n.decode(path, raw, depth)
+	}
+
+	return nil, n.fail(path, "unsupported type %s", rv.Type())
+}
+
+// enter marks a reference as on the current path, failing if it already is.
+func (n *normalizer) enter(path string, ptr uintptr) error {
+	if n.visiting[ptr] {
+		return n.fail(path, "cyclic reference")
+	}
+	n.visiting[ptr] = true
+	return nil
+}
+
+func (n *normalizer) object(path string, rv reflect.Value, depth int) (interface{}, error) {
+	if n.limits.MaxDepth > 0 && depth > n.limits.MaxDepth {
+		return nil, n.fail(path, "nested deeper than %d levels", n.limits.MaxDepth)
+	}
+	if n.limits.MaxKeys > 0 && rv.Len() > n.limits.MaxKeys {
+		return nil, n.fail(path, "has %d keys, more than %d", rv.Len(), n.limits.MaxKeys)
+	}
+	if err := n.enter(path, rv.Pointer()); err != nil {
+		return nil, err
+	}
+	defer delete(n.visiting, rv.Pointer())
+
+	// Sorted so the first error reported is deterministic
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	obj := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		name := key.String()
+		v, err := n.normalize(path+"."+name, rv.MapIndex(key), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		obj[name] = v
+	}
+	return obj, nil
+}
+
+func (n *normalizer) array(path string, rv reflect.Value, depth int) (interface{}, error) {
+	if n.limits.MaxDepth > 0 && depth > n.limits.MaxDepth {
+		return nil, n.fail(path, "nested deeper than %d levels", n.limits.MaxDepth)
+	}
+	arr := make([]interface{}, rv.Len())
+	for i := range arr {
+		v, err := n.normalize(path+"["+strconv.Itoa(i)+"]", rv.Index(i), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return arr, nil
+}
+
+func (n *normalizer) string(path, s string) (interface{}, error) {
+	if n.limits.MaxStringLength > 0 && len(s) > n.limits.MaxStringLength {
+		return nil, n.fail(path, "string of %d bytes is longer than %d", len(s), n.limits.MaxStringLength)
+	}
+	return s, nil
+}
+
+// number converts a json.Number to an int64, or a float64 if it is not an
+// integer or out of range, so that numbers decoded from structs and
+// json.Marshaler values have the same types as numbers set directly.
+func (n *normalizer) number(path string, num json.Number) (interface{}, error) {
+	if i, err := num.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := num.Float64()
+	if err != nil || math.IsInf(f, 0) {
+		return nil, n.fail(path, "%q is not a JSON number", string(num))
+	}
+	return f, nil
+}
+
+// decode normalizes the JSON produced by a json.Marshaler or struct, so
+// that limits apply to it too.
+func (n *normalizer) decode(path string, raw []byte, depth int) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, n.fail(path, "%v", err)
+	}
+	return n.normalize(path, reflect.ValueOf(v), depth)
+}
+
+func isNilValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package entrolytics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client sending to a test server, and the bodies
+// the server received.
+func newTestClient(t *testing.T, opts ClientOptions) (*Client, *[][]byte) {
+	t.Helper()
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	if opts.APIKey == "" {
+		opts.APIKey = "ent_test"
+	}
+	opts.Host = srv.URL
+	return NewClientWithOptions(opts), &bodies
+}
+
+type testItem struct {
+	SKU   string  `json:"sku"`
+	Price float64 `json:"price"`
+	Qty   int     `json:"qty"`
+}
+
+type testRawItem struct{}
+
+func (testRawItem) MarshalJSON() ([]byte, error) {
+	return []byte(`{"sku":"a","price":3,"qty":1}`), nil
+}
+
+func TestTrackStructDataWithTrackingPlan(t *testing.T) {
+	plan, err := ParseTrackingPlan([]byte(`{
+		"events": {
+			"purchase": {
+				"properties": {
+					"item": {
+						"type": "object",
+						"properties": {
+							"sku":   {"type": "string"},
+							"price": {"type": "number"},
+							"qty":   {"type": "integer", "enum": [1, 2, 3]}
+						}
+					},
+					"total": {"type": "number"}
+				}
+			}
+		}
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "struct",
+			data: map[string]interface{}{"item": testItem{SKU: "a", Price: 3, Qty: 1}},
+		},
+		{
+			name: "struct pointer with fractional price",
+			data: map[string]interface{}{"item": &testItem{SKU: "a", Price: 3.5, Qty: 2}},
+		},
+		{
+			name: "json.Marshaler",
+			data: map[string]interface{}{"item": testRawItem{}},
+		},
+		{
+			name: "json.Number",
+			data: map[string]interface{}{"total": json.Number("3")},
+		},
+		{
+			name: "json.Number fraction",
+			data: map[string]interface{}{"total": json.Number("3.25")},
+		},
+		{
+			name:    "struct violating enum",
+			data:    map[string]interface{}{"item": testItem{SKU: "a", Price: 3, Qty: 7}},
+			wantErr: "data.item.qty: must be one of [1 2 3]",
+		},
+		{
+			name:    "string where number declared",
+			data:    map[string]interface{}{"total": "3"},
+			wantErr: "data.total: must be a number, got string",
+		},
+		{
+			name:    "invalid json.Number",
+			data:    map[string]interface{}{"total": json.Number("three")},
+			wantErr: `entrolytics: invalid data.total: "three" is not a JSON number`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, bodies := newTestClient(t, ClientOptions{TrackingPlan: plan})
+			err := client.Track(Event{WebsiteID: "site", Name: "purchase", Data: tt.data})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Empty(t, *bodies)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, *bodies, 1)
+		})
+	}
+}
+
+func TestTrackingPlanValidateJSONNumber(t *testing.T) {
+	plan := &TrackingPlan{Events: map[string]*PropertySchema{
+		"e": {Properties: map[string]*PropertySchema{
+			"n": {Type: "integer"},
+			"s": {Type: "string"},
+		}},
+	}}
+
+	assert.NoError(t, plan.Validate("e", map[string]interface{}{"n": json.Number("3")}))
+
+	err := plan.Validate("e", map[string]interface{}{"s": json.Number("3")})
+	var schemaErr *SchemaValidationError
+	require.True(t, errors.As(err, &schemaErr))
+	assert.Equal(t, []SchemaViolation{{Path: "data.s", Message: "must be a string, got json.Number"}}, schemaErr.Violations)
+}
+
+func TestNormalizeDataLimitsAreOptIn(t *testing.T) {
+	data := map[string]interface{}{"s": strings.Repeat("x", 1<<20)}
+	for i := 0; i < 500; i++ {
+		data["k"+strconv.Itoa(i)] = i
+	}
+
+	got, err := NormalizeData("data", data, DataLimits{})
+	require.NoError(t, err)
+	assert.Len(t, got, len(data))
+
+	_, err = NormalizeData("data", data, RecommendedDataLimits)
+	var dataErr *DataValidationError
+	require.True(t, errors.As(err, &dataErr))
+	assert.Equal(t, "data", dataErr.Path)
+	assert.Equal(t, "has 501 keys, more than 100", dataErr.Message)
+}
+
+type testLevel int
+
+func (l testLevel) String() string { return "level-" + strconv.Itoa(int(l)) }
+
+type testCode string
+
+func (c testCode) MarshalText() ([]byte, error) { return []byte(strings.ToUpper(string(c))), nil }
+
+func TestNormalizeData(t *testing.T) {
+	selfMap := map[string]interface{}{}
+	selfMap["self"] = selfMap
+	selfSlice := []interface{}{nil}
+	selfSlice[0] = selfSlice
+	selfPointer := new(interface{})
+	*selfPointer = selfPointer
+	shared := map[string]interface{}{"x": 1}
+
+	tests := []struct {
+		name        string
+		value       interface{}
+		limits      DataLimits
+		want        interface{}
+		wantPath    string
+		wantMessage string
+	}{
+		{name: "nil", value: nil, want: nil},
+		{name: "integers", value: []interface{}{int8(-1), uint16(2), 3}, want: []interface{}{int64(-1), uint64(2), int64(3)}},
+		{name: "float32", value: float32(0.5), want: 0.5},
+		{name: "time", value: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC), want: "2024-01-02T03:04:05.000000006Z"},
+		{name: "Stringer", value: testLevel(3), want: "level-3"},
+		{name: "TextMarshaler", value: testCode("eur"), want: "EUR"},
+		{name: "error", value: errors.New("boom"), want: "boom"},
+		{name: "nil pointer", value: (*testItem)(nil), want: nil},
+		{name: "bytes", value: []byte("hi"), want: []byte("hi")},
+		{name: "struct", value: testItem{SKU: "a", Price: 1.5, Qty: 2}, want: map[string]interface{}{"sku": "a", "price": 1.5, "qty": int64(2)}},
+		{name: "json.Number", value: json.Number("1e3"), want: float64(1000)},
+		{name: "shared reference", value: []interface{}{shared, shared}, want: []interface{}{map[string]interface{}{"x": int64(1)}, map[string]interface{}{"x": int64(1)}}},
+		{name: "NaN", value: math.NaN(), wantPath: "data.v", wantMessage: "NaN is not a JSON number"},
+		{name: "infinity in array", value: []float64{1, math.Inf(-1)}, wantPath: "data.v[1]", wantMessage: "-Inf is not a JSON number"},
+		{name: "channel", value: make(chan int), wantPath: "data.v", wantMessage: "unsupported type chan int"},
+		{name: "func", value: func() {}, wantPath: "data.v", wantMessage: "unsupported type func()"},
+		{name: "complex", value: complex(1, 2), wantPath: "data.v", wantMessage: "unsupported type complex128"},
+		{name: "non-string keys", value: map[int]string{1: "a"}, wantPath: "data.v", wantMessage: "map keys must be strings, got int"},
+		{name: "map cycle", value: selfMap, wantPath: "data.v.self", wantMessage: "cyclic reference"},
+		{name: "slice cycle", value: selfSlice, wantPath: "data.v[0]", wantMessage: "cyclic reference"},
+		{name: "pointer cycle", value: selfPointer, wantPath: "data.v", wantMessage: "cyclic reference"},
+		{
+			name:        "nested path",
+			value:       map[string]interface{}{"items": []interface{}{map[string]interface{}{"price": math.Inf(1)}}},
+			wantPath:    "data.v.items[0].price",
+			wantMessage: "+Inf is not a JSON number",
+		},
+		{
+			name:        "string limit",
+			value:       map[string]string{"ok": "abc", "long": "abcd"},
+			limits:      DataLimits{MaxStringLength: 3},
+			wantPath:    "data.v.long",
+			wantMessage: "string of 4 bytes is longer than 3",
+		},
+		{
+			name:        "string limit applies to Stringer",
+			value:       testLevel(10),
+			limits:      DataLimits{MaxStringLength: 7},
+			wantPath:    "data.v",
+			wantMessage: "string of 8 bytes is longer than 7",
+		},
+		{
+			name:        "key limit",
+			value:       map[string]int{"a": 1, "b": 2, "c": 3},
+			limits:      DataLimits{MaxKeys: 2},
+			wantPath:    "data.v",
+			wantMessage: "has 3 keys, more than 2",
+		},
+		{
+			name:   "depth within limit",
+			value:  []interface{}{1},
+			limits: DataLimits{MaxDepth: 2},
+			want:   []interface{}{int64(1)},
+		},
+		{
+			name:        "depth limit",
+			value:       map[string]interface{}{"a": []int{1}},
+			limits:      DataLimits{MaxDepth: 2},
+			wantPath:    "data.v.a",
+			wantMessage: "nested deeper than 2 levels",
+		},
+		{
+			name:        "depth limit applies to structs",
+			value:       []testItem{{SKU: "a"}},
+			limits:      DataLimits{MaxDepth: 2},
+			wantPath:    "data.v[0]",
+			wantMessage: "nested deeper than 2 levels",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeData("data", map[string]interface{}{"v": tt.value}, tt.limits)
+			if tt.wantMessage != "" {
+				var dataErr *DataValidationError
+				require.True(t, errors.As(err, &dataErr), "got %v", err)
+				assert.Equal(t, tt.wantPath, dataErr.Path)
+				assert.Equal(t, tt.wantMessage, dataErr.Message)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"v": tt.want}, got)
+		})
+	}
+}
@@ -0,0 +1,471 @@
+package entrolytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Properties is a typed, ordered set of event properties that is written
+// straight to the request JSON, without building a map. It can be used
+// wherever Data, Traits or Attribution are accepted, through Event.Properties,
+// PageView.Properties, Identify.TraitProperties and
+// WebVital.AttributionProperties. Setting a key again replaces its value.
+//
+// Example:
+//
+//	client.Track(entrolytics.Event{
+//	    WebsiteID: "abc123",
+//	    Name:      "purchase",
+//	    Properties: entrolytics.NewProperties().
+//	        Float("revenue", 99.99).
+//	        String("currency", "USD").
+//	        Strings("skus", skus),
+//	})
+type Properties struct {
+	fields []propertyField
+}
+
+type propertyKind uint8
+
+const (
+	propertyString propertyKind = iota
+	propertyInt
+	propertyFloat
+	propertyBool
+	propertyTime
+	propertyDuration
+	propertyObject
+	propertyStrings
+	propertyInts
+	propertyFloats
+	propertyObjects
+	propertyJSON
+)
+
+type propertyField struct {
+	key  string
+	kind propertyKind
+	str  string
+	num  int64
+	f    float64
+	b    bool
+	t    time.Time
+	v    interface{} // *Properties, slices or json.Marshaler
+}
+
+// NewProperties returns an empty property set.
+func NewProperties() *Properties {
+	return &Properties{}
+}
+
+func (p *Properties) set(field propertyField) *Properties {
+	for i := range p.fields {
+		if p.fields[i].key == field.key {
+			p.fields[i] = field
+			return p
+		}
+	}
+	p.fields = append(p.fields, field)
+	return p
+}
+
+// String sets a string property.
+func (p *Properties) String(key, value string) *Properties {
+	return p.set(propertyField{key: key, kind: propertyString, str: value})
+}
+
+// Int sets an integer property.
+func (p *Properties) Int(key string, value int) *Properties {
+	return p.set(propertyField{key: key, kind: propertyInt, num: int64(value)})
+}
+
+// Int64 sets an integer property.
+func (p *Properties) Int64(key string, value int64) *Properties {
+	return p.set(propertyField{key: key, kind: propertyInt, num: value})
+}
+
+// Float sets a number property. NaN and infinite values are rejected when sending.
+func (p *Properties) Float(key string, value float64) *Properties {
+	return p.set(propertyField{key: key, kind: propertyFloat, f: value})
+}
+
+// Bool sets a boolean property.
+func (p *Properties) Bool(key string, value bool) *Properties {
+	return p.set(propertyField{key: key, kind: propertyBool, b: value})
+}
+
+// Time sets a time property, sent as an RFC 3339 string.
+func (p *Properties) Time(key string, value time.Time) *Properties {
+	return p.set(propertyField{key: key, kind: propertyTime, t: value})
+}
+
+// Duration sets a duration property, sent as whole milliseconds.
+func (p *Properties) Duration(key string, value time.Duration) *Properties {
+	return p.set(propertyField{key: key, kind: propertyDuration, num: value.Milliseconds()})
+}
+
+// Object sets a nested object property.
+func (p *Properties) Object(key string, value *Properties) *Properties {
+	return p.set(propertyField{key: key, kind: propertyObject, v: value})
+}
+
+// Strings sets a string array property.
+func (p *Properties) Strings(key string, values []string) *Properties {
+	return p.set(propertyField{key: key, kind: propertyStrings, v: values})
+}
+
+// Ints sets an integer array property.
+func (p *Properties) Ints(key string, values []int) *Properties {
+	return p.set(propertyField{key: key, kind: propertyInts, v: values})
+}
+
+// Floats sets a number array property.
+func (p *Properties) Floats(key string, values []float64) *Properties {
+	return p.set(propertyField{key: key, kind: propertyFloats, v: values})
+}
+
+// Objects sets an object array property.
+func (p *Properties) Objects(key string, values []*Properties) *Properties {
+	return p.set(propertyField{key: key, kind: propertyObjects, v: values})
+}
+
+// JSON sets a property to a value that marshals itself. A nil value is
+// sent as null.
+func (p *Properties) JSON(key string, value json.Marshaler) *Properties {
+	return p.set(propertyField{key: key, kind: propertyJSON, v: value})
+}
+
+// Merge sets every property of other, replacing properties with the same
+// key. It lets property sets shared by several events be combined.
+func (p *Properties) Merge(other *Properties) *Properties {
+	for _, field := range other.all() {
+		p.set(field)
+	}
+	return p
+}
+
+// Len returns the number of properties. A nil *Properties is empty.
+func (p *Properties) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.fields)
+}
+
+func (p *Properties) all() []propertyField {
+	if p == nil {
+		return nil
+	}
+	return p.fields
+}
+
+// Map returns the properties as a map of JSON-compatible values, with the
+// same types NormalizeData produces. It fails if a JSON value cannot be
+// marshaled.
+func (p *Properties) Map() (map[string]interface{}, error) {
+	if p == nil {
+		return nil, nil
+	}
+	m := make(map[string]interface{}, len(p.fields))
+	for _, field := range p.fields {
+		v, err := field.value()
+		if err != nil {
+			return nil, err
+		}
+		m[field.key] = v
+	}
+	return m, nil
+}
+
+func (field *propertyField) value() (interface{}, error) {
+	switch field.kind {
+	case propertyString:
+		return field.str, nil
+	case propertyInt, propertyDuration:
+		return field.num, nil
+	case propertyFloat:
+		return field.f, nil
+	case propertyBool:
+		return field.b, nil
+	case propertyTime:
+		return field.t.Format(time.RFC3339Nano), nil
+	case propertyObject:
+		return field.v.(*Properties).Map()
+	case propertyObjects:
+		objects := field.v.([]*Properties)
+		values := make([]interface{}, len(objects))
+		for i, obj := range objects {
+			m, err := obj.Map()
+			if err != nil {
+				return nil, err
+			}
+			values[i] = m
+		}
+		return values, nil
+	case propertyJSON:
+		// Limits were checked by validate; only convert the value here
+		n := &normalizer{visiting: make(map[uintptr]bool)}
+		return n.normalize(field.key, reflect.ValueOf(field.v), 1)
+	}
+	return field.v, nil
+}
+
+// MarshalJSON writes the properties as a JSON object.
+func (p *Properties) MarshalJSON() ([]byte, error) {
+	return p.appendJSON(make([]byte, 0, 64*p.Len()+2))
+}
+
+func (p *Properties) appendJSON(buf []byte) ([]byte, error) {
+	if p == nil {
+		return append(buf, "null"...), nil
+	}
+	var err error
+	buf = append(buf, '{')
+	for i, field := range p.fields {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendJSONString(buf, field.key)
+		buf = append(buf, ':')
+
+		switch field.kind {
+		case propertyString:
+			buf = appendJSONString(buf, field.str)
+		case propertyInt, propertyDuration:
+			buf = strconv.AppendInt(buf, field.num, 10)
+		case propertyFloat:
+			if buf, err = appendJSONFloat(buf, field.key, field.f); err != nil {
+				return nil, err
+			}
+		case propertyBool:
+			buf = strconv.AppendBool(buf, field.b)
+		case propertyTime:
+			buf = append(buf, '"')
+			buf = field.t.AppendFormat(buf, time.RFC3339Nano)
+			buf = append(buf, '"')
+		case propertyObject:
+			if buf, err = field.v.(*Properties).appendJSON(buf); err != nil {
+				return nil, err
+			}
+		case propertyStrings:
+			buf = append(buf, '[')
+			for j, s := range field.v.([]string) {
+				if j > 0 {
+					buf = append(buf, ',')
+				}
+				buf = appendJSONString(buf, s)
+			}
+			buf = append(buf, ']')
+		case propertyInts:
+			buf = append(buf, '[')
+			for j, n := range field.v.([]int) {
+				if j > 0 {
+					buf = append(buf, ',')
+				}
+				buf = strconv.AppendInt(buf, int64(n), 10)
+			}
+			buf = append(buf, ']')
+		case propertyFloats:
+			buf = append(buf, '[')
+			for j, f := range field.v.([]float64) {
+				if j > 0 {
+					buf = append(buf, ',')
+				}
+				if buf, err = appendJSONFloat(buf, field.key, f); err != nil {
+					return nil, err
+				}
+			}
+			buf = append(buf, ']')
+		case propertyObjects:
+			buf = append(buf, '[')
+			for j, obj := range field.v.([]*Properties) {
+				if j > 0 {
+					buf = append(buf, ',')
+				}
+				if buf, err = obj.appendJSON(buf); err != nil {
+					return nil, err
+				}
+			}
+			buf = append(buf, ']')
+		case propertyJSON:
+			if field.v == nil || isNilValue(reflect.ValueOf(field.v)) {
+				buf = append(buf, "null"...)
+				continue
+			}
+			raw, err := field.v.(json.Marshaler).MarshalJSON()
+			if err != nil {
+				return nil, fmt.Errorf("entrolytics: property %q: %w", field.key, err)
+			}
+			buf = append(buf, raw...)
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+// validate checks the properties against limits, reporting errors with
+// paths below path.
+func (p *Properties) validate(path string, limits DataLimits) error {
+	if p.Len() == 0 {
+		return nil
+	}
+	n := &normalizer{limits: limits, visiting: make(map[uintptr]bool)}
+	return n.properties(path, p, 1)
+}
+
+func (n *normalizer) properties(path string, p *Properties, depth int) error {
+	if p == nil {
+		return nil
+	}
+	if n.limits.MaxDepth > 0 && depth > n.limits.MaxDepth {
+		return n.fail(path, "nested deeper than %d levels", n.limits.MaxDepth)
+	}
+	if n.limits.MaxKeys > 0 && len(p.fields) > n.limits.MaxKeys {
+		return n.fail(path, "has %d keys, more than %d", len(p.fields), n.limits.MaxKeys)
+	}
+	if err := n.enter(path, reflect.ValueOf(p).Pointer()); err != nil {
+		return err
+	}
+	defer delete(n.visiting, reflect.ValueOf(p).Pointer())
+
+	for _, field := range p.fields {
+		fieldPath := path + "." + field.key
+		var err error
+		switch field.kind {
+		case propertyString:
+			_, err = n.string(fieldPath, field.str)
+		case propertyFloat:
+			_, err = n.normalize(fieldPath, reflect.ValueOf(field.f), depth+1)
+		case propertyObject:
+			err = n.properties(fieldPath, field.v.(*Properties), depth+1)
+		case propertyStrings, propertyFloats:
+			_, err = n.normalize(fieldPath, reflect.ValueOf(field.v), depth+1)
+		case propertyObjects:
+			if n.limits.MaxDepth > 0 && depth+1 > n.limits.MaxDepth {
+				return n.fail(fieldPath, "nested deeper than %d levels", n.limits.MaxDepth)
+			}
+			for i, obj := range field.v.([]*Properties) {
+				if err = n.properties(fieldPath+"["+strconv.Itoa(i)+"]", obj, depth+2); err != nil {
+					break
+				}
+			}
+		case propertyJSON:
+			_, err = n.normalize(fieldPath, reflect.ValueOf(field.v), depth+1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// payloadData combines normalized map data with typed properties, which
+// take precedence. Properties alone are sent without building a map.
+func payloadData(data map[string]interface{}, props *Properties) (interface{}, error) {
+	switch {
+	case props.Len() == 0 && len(data) == 0:
+		return nil, nil
+	case props.Len() == 0:
+		return data, nil
+	case len(data) == 0:
+		return props, nil
+	}
+	return mergeProperties(data, props)
+}
+
+// mergeProperties returns a copy of data with the properties set.
+func mergeProperties(data map[string]interface{}, props *Properties) (map[string]interface{}, error) {
+	merged := make(map[string]interface{}, len(data)+props.Len())
+	for k, v := range data {
+		merged[k] = v
+	}
+	for _, field := range props.all() {
+		v, err := field.value()
+		if err != nil {
+			return nil, err
+		}
+		merged[field.key] = v
+	}
+	return merged, nil
+}
+
+// EventDefinition is a reusable event shape: an event name and a function
+// building its properties from a typed value.
+//
+// Example:
+//
+//	type Purchase struct {
+//	    Revenue  float64
+//	    Currency string
+//	}
+//
+//	var PurchaseEvent = entrolytics.DefineEvent("purchase", func(p Purchase) *entrolytics.Properties {
+//	    return entrolytics.NewProperties().Float("revenue", p.Revenue).String("currency", p.Currency)
+//	})
+//
+//	client.Track(PurchaseEvent.Event(Purchase{Revenue: 99.99, Currency: "USD"}))
+type EventDefinition[T any] struct {
+	// Name is the event name.
+	Name string
+
+	properties func(T) *Properties
+}
+
+// DefineEvent creates an event definition.
+func DefineEvent[T any](name string, properties func(T) *Properties) EventDefinition[T] {
+	return EventDefinition[T]{Name: name, properties: properties}
+}
+
+// Event returns an event with the definition's name and the properties of
+// value. Set WebsiteID and other fields on the result as needed.
+func (d EventDefinition[T]) Event(value T) Event {
+	return Event{Name: d.Name, Properties: d.properties(value)}
+}
+
+// appendJSONFloat appends f as a JSON number.
+func appendJSONFloat(buf []byte, key string, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("entrolytics: property %q: %v is not a JSON number", key, f)
+	}
+	return strconv.AppendFloat(buf, f, 'g', -1, 64), nil
+}
+
+// appendJSONString appends s as a JSON string. Invalid UTF-8 is replaced
+// with U+FFFD, as by encoding/json.
+func appendJSONString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, `�`...)
+		} else {
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,203 @@
+package entrolytics
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("boom")
+}
+
+type pointerMarshaler struct{ N int }
+
+func (m *pointerMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]int{"n": m.N})
+}
+
+func TestPropertiesMarshalJSON(t *testing.T) {
+	shared := NewProperties().String("plan", "pro").Int("seats", 3)
+
+	tests := []struct {
+		name    string
+		props   *Properties
+		want    string
+		wantErr string
+	}{
+		{name: "nil", props: nil, want: `null`},
+		{name: "empty", props: NewProperties(), want: `{}`},
+		{
+			name: "scalars in order",
+			props: NewProperties().
+				String("s", "v").
+				Int("i", -1).
+				Int64("i64", 1<<40).
+				Float("f", 0.5).
+				Bool("b", true).
+				Time("t", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)).
+				Duration("d", 1500*time.Millisecond),
+			want: `{"s":"v","i":-1,"i64":1099511627776,"f":0.5,"b":true,"t":"2024-01-02T03:04:05Z","d":1500}`,
+		},
+		{
+			name:  "escaping",
+			props: NewProperties().String("q\"k", "a\"b\\c\n\r\t\x01<>&\xffé"),
+			want:  `{"q\"k":"a\"b\\c\n\r\t\u0001<>&�é"}`,
+		},
+		{
+			name:  "replaced key keeps position",
+			props: NewProperties().String("a", "1").String("b", "2").String("a", "3"),
+			want:  `{"a":"3","b":"2"}`,
+		},
+		{
+			name: "arrays and nested objects",
+			props: NewProperties().
+				Strings("tags", []string{"x", "y"}).
+				Ints("ids", []int{1, 2}).
+				Floats("scores", []float64{1.5}).
+				Object("user", NewProperties().String("id", "u1")).
+				Objects("items", []*Properties{NewProperties().Int("n", 1), nil}),
+			want: `{"tags":["x","y"],"ids":[1,2],"scores":[1.5],"user":{"id":"u1"},"items":[{"n":1},null]}`,
+		},
+		{
+			name:  "merge",
+			props: NewProperties().Int("seats", 1).String("source", "ad").Merge(shared),
+			want:  `{"seats":3,"source":"ad","plan":"pro"}`,
+		},
+		{
+			name:  "json",
+			props: NewProperties().JSON("m", &pointerMarshaler{N: 2}),
+			want:  `{"m":{"n":2}}`,
+		},
+		{
+			name:    "NaN",
+			props:   NewProperties().Float("f", math.NaN()),
+			wantErr: `entrolytics: property "f": NaN is not a JSON number`,
+		},
+		{
+			name:    "infinite array element",
+			props:   NewProperties().Floats("fs", []float64{1, math.Inf(1)}),
+			wantErr: `entrolytics: property "fs": +Inf is not a JSON number`,
+		},
+		{
+			name:    "nested NaN",
+			props:   NewProperties().Object("o", NewProperties().Float("f", math.NaN())),
+			wantErr: `entrolytics: property "f": NaN is not a JSON number`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.props.MarshalJSON()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+			assert.True(t, json.Valid(b))
+		})
+	}
+}
+
+func TestPayloadDataMergesProperties(t *testing.T) {
+	data := map[string]interface{}{"plan": "free", "source": "ad"}
+	props := NewProperties().String("plan", "pro")
+
+	got, err := payloadData(data, props)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"plan": "pro", "source": "ad"}, got)
+	assert.Equal(t, "free", data["plan"], "data must not be modified")
+
+	got, err = payloadData(nil, props)
+	require.NoError(t, err)
+	assert.Same(t, props, got)
+
+	got, err = payloadData(nil, NewProperties())
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestPropertiesNilJSON(t *testing.T) {
+	for name, value := range map[string]json.Marshaler{
+		"nil interface": nil,
+		"nil pointer":   (*pointerMarshaler)(nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			props := NewProperties().JSON("k", value)
+
+			b, err := json.Marshal(props)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"k":null}`, string(b))
+
+			m, err := props.Map()
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"k": nil}, m)
+
+			client, bodies := newTestClient(t, ClientOptions{})
+			require.NoError(t, client.Track(Event{WebsiteID: "site", Name: "e", Properties: props}))
+			require.Len(t, *bodies, 1)
+			assert.Contains(t, string((*bodies)[0]), `"data":{"k":null}`)
+		})
+	}
+}
+
+func TestPropertiesJSONError(t *testing.T) {
+	props := NewProperties().JSON("k", failingMarshaler{})
+
+	_, err := props.Map()
+	assert.ErrorContains(t, err, "boom")
+
+	_, err = json.Marshal(props)
+	assert.ErrorContains(t, err, "boom")
+
+	client, bodies := newTestClient(t, ClientOptions{})
+	err = client.Track(Event{WebsiteID: "site", Name: "e", Properties: props})
+	assert.ErrorContains(t, err, "boom")
+	assert.Empty(t, *bodies)
+}
+
+func TestPropertiesJSONWithTrackingPlan(t *testing.T) {
+	plan := &TrackingPlan{Events: map[string]*PropertySchema{
+		"purchase": {Properties: map[string]*PropertySchema{
+			"item": {Type: "object", Properties: map[string]*PropertySchema{
+				"n": {Type: "integer"},
+			}},
+			"total": {Type: "number"},
+		}},
+	}}
+	client, bodies := newTestClient(t, ClientOptions{TrackingPlan: plan})
+
+	err := client.Track(Event{
+		WebsiteID: "site",
+		Name:      "purchase",
+		Data:      map[string]interface{}{"total": 3},
+		Properties: NewProperties().
+			JSON("item", &pointerMarshaler{N: 2}),
+	})
+	require.NoError(t, err)
+	require.Len(t, *bodies, 1)
+	assert.Contains(t, string((*bodies)[0]), `"item":{"n":2}`)
+}
+
+func TestTrackerProperties(t *testing.T) {
+	client, bodies := newTestClient(t, ClientOptions{})
+	tracker := NewTracker(client, "site", nil)
+	tracker.UserID = "user_1"
+
+	require.NoError(t, tracker.TrackProperties("signup", NewProperties().String("plan", "pro")))
+	require.NoError(t, tracker.IdentifyProperties(NewProperties().Bool("admin", true)))
+	require.Len(t, *bodies, 2)
+	assert.Contains(t, string((*bodies)[0]), `"data":{"plan":"pro"}`)
+	assert.Contains(t, string((*bodies)[1]), `"traits":{"admin":true}`)
+
+	var nilTracker *Tracker
+	assert.ErrorIs(t, nilTracker.TrackProperties("signup", nil), ErrNoTracker)
+}
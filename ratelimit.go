@@ -0,0 +1,80 @@
+package entrolytics
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiterOptions configures the client-side token-bucket rate limiter.
+// Each endpoint has its own bucket.
+type RateLimiterOptions struct {
+	// Rate is the sustained number of requests per second allowed to each endpoint.
+	Rate float64
+
+	// Burst is the number of requests that may be sent at once.
+	// Defaults to Rate rounded up, and at least 1.
+	Burst int
+
+	// Endpoints overrides Rate for specific endpoints: the event endpoint
+	// (ClientOptions.Endpoint), "/api/collect/vitals", "/api/collect/forms",
+	// or "/api/collect/vitals/summary" for VitalAggregator summaries.
+	Endpoints map[string]float64
+}
+
+type rateLimiter struct {
+	opts RateLimiterOptions
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket holds up to burst tokens, refilled at rate tokens per second.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(opts *RateLimiterOptions) *rateLimiter {
+	if opts == nil {
+		return nil
+	}
+	return &rateLimiter{
+		opts:    *opts,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token for the endpoint if one is available.
+func (l *rateLimiter) allow(endpoint string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[endpoint]
+	if !ok {
+		rate := l.opts.Rate
+		if r, ok := l.opts.Endpoints[endpoint]; ok {
+			rate = r
+		}
+		if rate <= 0 {
+			return true
+		}
+		burst := float64(l.opts.Burst)
+		if burst <= 0 {
+			burst = math.Max(1, math.Ceil(rate))
+		}
+		b = &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+		l.buckets[endpoint] = b
+	}
+
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,107 @@
+package entrolytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	type request struct {
+		endpoint string
+		after    time.Duration // since the previous request
+		want     bool
+	}
+
+	tests := []struct {
+		name     string
+		opts     RateLimiterOptions
+		requests []request
+	}{
+		{
+			name: "burst defaults to rate",
+			opts: RateLimiterOptions{Rate: 2},
+			requests: []request{
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: false},
+			},
+		},
+		{
+			name: "fractional rate allows one",
+			opts: RateLimiterOptions{Rate: 0.5},
+			requests: []request{
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", after: time.Second, want: false},
+				{endpoint: "/a", after: time.Second, want: true},
+			},
+		},
+		{
+			name: "explicit burst",
+			opts: RateLimiterOptions{Rate: 1, Burst: 3},
+			requests: []request{
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: false},
+			},
+		},
+		{
+			name: "refills at rate up to burst",
+			opts: RateLimiterOptions{Rate: 10, Burst: 2},
+			requests: []request{
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: false},
+				{endpoint: "/a", after: 100 * time.Millisecond, want: true},
+				{endpoint: "/a", want: false},
+				{endpoint: "/a", after: time.Hour, want: true},
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: false},
+			},
+		},
+		{
+			name: "endpoints have separate buckets",
+			opts: RateLimiterOptions{Rate: 1},
+			requests: []request{
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: false},
+				{endpoint: "/b", want: true},
+			},
+		},
+		{
+			name: "endpoint override",
+			opts: RateLimiterOptions{Rate: 1, Burst: 1, Endpoints: map[string]float64{"/vitals": 0}},
+			requests: []request{
+				{endpoint: "/send", want: true},
+				{endpoint: "/send", want: false},
+				{endpoint: "/vitals", want: true},
+				{endpoint: "/vitals", want: true},
+			},
+		},
+		{
+			name: "zero rate is unlimited",
+			opts: RateLimiterOptions{Endpoints: map[string]float64{"/a": 1}},
+			requests: []request{
+				{endpoint: "/b", want: true},
+				{endpoint: "/b", want: true},
+				{endpoint: "/a", want: true},
+				{endpoint: "/a", want: false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(&tt.opts)
+			for i, req := range tt.requests {
+				// Move the buckets back in time instead of sleeping
+				for _, b := range l.buckets {
+					b.last = b.last.Add(-req.after)
+				}
+				assert.Equal(t, req.want, l.allow(req.endpoint), "request %d", i)
+			}
+		})
+	}
+}
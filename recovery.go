@@ -0,0 +1,218 @@
+package entrolytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrorEventName is the event name used for tracked panics and server errors.
+const ErrorEventName = "$error"
+
+// DefaultMaxStackSize is the default stack trace size limit, in bytes.
+const DefaultMaxStackSize = 4096
+
+// DefaultMaxMessageSize is the default error message size limit, in bytes.
+const DefaultMaxMessageSize = 256
+
+// ErrorOptions configures the error tracking middleware.
+type ErrorOptions struct {
+	// Repanic re-panics after tracking a recovered panic, so outer recovery
+	// middleware or the server can handle it. Otherwise the middleware
+	// responds with 500 Internal Server Error if nothing was written yet.
+	Repanic bool
+
+	// SkipServerErrors disables tracking of 5xx responses that did not panic.
+	SkipServerErrors bool
+
+	// MaxStackSize truncates the stack trace sent with panics, in bytes.
+	// Defaults to DefaultMaxStackSize. Set to -1 to omit stack traces.
+	MaxStackSize int
+
+	// MaxMessageSize truncates the panic message sent with panics, in
+	// bytes. Messages can carry request data such as URLs or credentials.
+	// Defaults to DefaultMaxMessageSize. Set to -1 to omit messages.
+	MaxMessageSize int
+
+	// GetRoutePattern extracts the route template for routers other than
+	// http.ServeMux. See MiddlewareOptions.GetRoutePattern.
+	GetRoutePattern func(r *http.Request) string
+
+	// GetUserID is a function to extract user ID from the request.
+	GetUserID func(r *http.Request) string
+
+	// GetSessionID is a function to extract session ID from the request.
+	GetSessionID func(r *http.Request) string
+
+	// OnError is called when an error occurs during asynchronous tracking.
+	OnError func(err error)
+}
+
+// ErrorMiddleware creates HTTP middleware that recovers panics and tracks
+// them, along with 5xx responses, as ErrorEventName events. Panic events
+// include the panic message and stack trace, truncated to MaxMessageSize
+// and MaxStackSize.
+//
+// Example:
+//
+//	handler := entrolytics.ErrorMiddleware(client, "website_id", entrolytics.ErrorOptions{})(mux)
+func ErrorMiddleware(client *Client, websiteID string, opts ErrorOptions) func(http.Handler) http.Handler {
+	maxStackSize := opts.MaxStackSize
+	if maxStackSize == 0 {
+		maxStackSize = DefaultMaxStackSize
+	}
+	maxMessageSize := opts.MaxMessageSize
+	if maxMessageSize == 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+	routeOpts := MiddlewareOptions{UseRoutePattern: true, GetRoutePattern: opts.GetRoutePattern}
+
+	track := func(r *http.Request, data map[string]interface{}) {
+		data["route"] = routeOpts.resolvePath(r)
+		data["method"] = r.Method
+
+		event := Event{
+			WebsiteID: websiteID,
+			Name:      ErrorEventName,
+			Data:      data,
+			URL:       r.URL.Path,
+			Referrer:  r.Referer(),
+			UserAgent: r.UserAgent(),
+			IPAddress: getClientIP(r),
+		}
+		if opts.GetUserID != nil {
+			event.UserID = opts.GetUserID(r)
+		}
+		if opts.GetSessionID != nil {
+			event.SessionID = opts.GetSessionID(r)
+		}
+
+		// The request context is canceled once the response is done
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			if err := client.TrackWithContext(ctx, event); err != nil && opts.OnError != nil {
+				opts.OnError(err)
+			}
+		}()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rr := NewResponseRecorder(w)
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				// ErrAbortHandler is a deliberate abort, not an error
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				status := http.StatusInternalServerError
+				if rr.wroteHeader {
+					status = rr.StatusCode
+				}
+
+				errorType, message := describePanic(recovered)
+				data := map[string]interface{}{
+					"status":      status,
+					"error_type":  errorType,
+					"fingerprint": Fingerprint(errorType, panicFrames()),
+				}
+				if maxMessageSize > 0 {
+					data["message"] = TruncateMessage(message, maxMessageSize)
+				}
+				if maxStackSize > 0 {
+					data["stack"] = truncate(string(debug.Stack()), maxStackSize)
+				}
+				track(r, data)
+
+				if opts.Repanic {
+					panic(recovered)
+				}
+				if !rr.wroteHeader {
+					http.Error(rr, http.StatusText(status), status)
+				}
+			}()
+
+			next.ServeHTTP(rr, r)
+
+			if !opts.SkipServerErrors && rr.StatusCode >= 500 && !rr.Hijacked {
+				errorType := "http_" + strconv.Itoa(rr.StatusCode)
+				track(r, map[string]interface{}{
+					"status":      rr.StatusCode,
+					"error_type":  errorType,
+					"fingerprint": Fingerprint(errorType, []string{routeOpts.resolvePath(r)}),
+				})
+			}
+		})
+	}
+}
+
+// describePanic returns the type and message of a recovered panic value.
+func describePanic(recovered interface{}) (errorType, message string) {
+	errorType = fmt.Sprintf("%T", recovered)
+	if err, ok := recovered.(error); ok {
+		return errorType, err.Error()
+	}
+	return errorType, fmt.Sprint(recovered)
+}
+
+// panicFrames returns the function names of the frames that panicked,
+// skipping the runtime and this middleware.
+func panicFrames() []string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(0, pcs)])
+
+	var names []string
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		switch {
+		case frame.Function == "runtime.gopanic":
+			panicking = true
+		case panicking && !strings.HasPrefix(frame.Function, "runtime."):
+			names = append(names, frame.Function)
+		}
+		if !more || len(names) == 5 {
+			return names
+		}
+	}
+}
+
+// Fingerprint groups errors of the same type raised from the same place,
+// identified by stack frames or, without a stack, by route.
+func Fingerprint(errorType string, frames []string) string {
+	sum := sha256.Sum256([]byte(errorType + "\n" + strings.Join(frames, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// TruncateMessage shortens an error message to at most n bytes, without
+// splitting a UTF-8 sequence. Framework adapters use it with
+// DefaultMaxMessageSize for the messages of their error events.
+func TruncateMessage(message string, n int) string {
+	if len(message) <= n {
+		return message
+	}
+	for n > 0 && !utf8.RuneStart(message[n]) {
+		n--
+	}
+	return message[:n]
+}
+
+// truncate shortens s to at most n bytes.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
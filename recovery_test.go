@@ -0,0 +1,25 @@
+package entrolytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		n       int
+		want    string
+	}{
+		{message: "short", n: 10, want: "short"},
+		{message: "exactly", n: 7, want: "exactly"},
+		{message: "token=secret", n: 5, want: "token"},
+		{message: "héllo", n: 2, want: "h"},
+		{message: "héllo", n: 3, want: "hé"},
+		{message: "é", n: 1, want: ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, TruncateMessage(tt.message, tt.n), "%q to %d bytes", tt.message, tt.n)
+	}
+}
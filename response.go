@@ -0,0 +1,91 @@
+package entrolytics
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder wraps http.ResponseWriter to capture the status code and
+// response size. It passes through http.Flusher, http.Hijacker and
+// io.ReaderFrom, and exposes the underlying writer to http.ResponseController
+// via Unwrap, so streaming responses and websockets keep working.
+type ResponseRecorder struct {
+	http.ResponseWriter
+
+	// StatusCode is the response status. Defaults to 200 if the handler never calls WriteHeader.
+	StatusCode int
+
+	// BytesWritten is the number of response body bytes written.
+	BytesWritten int64
+
+	// Hijacked reports whether the handler took over the connection.
+	Hijacked bool
+
+	wroteHeader bool
+}
+
+// NewResponseRecorder wraps w.
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w, StatusCode: http.StatusOK}
+}
+
+// WriteHeader captures the status code. Informational (1xx) responses other
+// than 101 Switching Protocols are passed through without being recorded.
+func (rr *ResponseRecorder) WriteHeader(code int) {
+	if !rr.wroteHeader && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		rr.StatusCode = code
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+// Write counts the bytes written.
+func (rr *ResponseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(b)
+	rr.BytesWritten += int64(n)
+	return n, err
+}
+
+// ReadFrom uses the underlying io.ReaderFrom if available, so sendfile
+// optimizations still apply, and counts the bytes copied.
+func (rr *ResponseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	rr.wroteHeader = true
+
+	var n int64
+	var err error
+	if rf, ok := rr.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		// Hide ReadFrom so io.Copy does not recurse into this method
+		n, err = io.Copy(struct{ io.Writer }{rr.ResponseWriter}, src)
+	}
+	rr.BytesWritten += n
+	return n, err
+}
+
+// Flush sends buffered data to the client, if the underlying writer supports it.
+func (rr *ResponseRecorder) Flush() {
+	rr.wroteHeader = true
+	_ = http.NewResponseController(rr.ResponseWriter).Flush()
+}
+
+// Hijack lets the handler take over the connection, if the underlying writer supports it.
+func (rr *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rr.ResponseWriter).Hijack()
+	if err == nil {
+		rr.Hijacked = true
+		if !rr.wroteHeader {
+			rr.StatusCode = http.StatusSwitchingProtocols
+			rr.wroteHeader = true
+		}
+	}
+	return conn, buf, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (rr *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
@@ -0,0 +1,116 @@
+package entrolytics
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// RoutePattern returns the route template that a Go 1.22+ http.ServeMux
+// matched for the request, without the method and host parts. For the
+// pattern "GET /users/{id}" it returns "/users/{id}". It returns an empty
+// string if the request was not routed by a ServeMux.
+//
+// http.ServeMux sets the pattern while routing, so middleware wrapping the
+// mux must call RoutePattern after the handler has run.
+func RoutePattern(r *http.Request) string {
+	return routeTemplate(r.Pattern)
+}
+
+// routeTemplate strips the method and host parts of a ServeMux pattern.
+func routeTemplate(pattern string) string {
+	if pattern == "" {
+		return ""
+	}
+
+	// Strip the method
+	if idx := strings.IndexAny(pattern, " \t"); idx != -1 {
+		pattern = strings.TrimLeft(pattern[idx:], " \t")
+	}
+
+	// Strip the host
+	if idx := strings.Index(pattern, "/"); idx > 0 {
+		pattern = pattern[idx:]
+	}
+
+	// "{$}" only anchors the match and is not part of the path
+	return strings.TrimSuffix(pattern, "{$}")
+}
+
+// PathRule rewrites path segments matching Pattern to Replacement.
+type PathRule struct {
+	// Pattern is matched against each path segment. Anchor it to match whole segments.
+	Pattern *regexp.Regexp
+
+	// Replacement is substituted for the matching segment.
+	Replacement string
+}
+
+// PathNormalizer collapses dynamic path segments such as IDs and UUIDs into
+// placeholders, so that /users/123 and /users/456 are reported as one page.
+type PathNormalizer struct {
+	rules []PathRule
+}
+
+// DefaultPathRules replace UUIDs, numeric IDs and long hex hashes.
+var DefaultPathRules = []PathRule{
+	{Pattern: regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), Replacement: "{uuid}"},
+	{Pattern: regexp.MustCompile(`^[0-9]+$`), Replacement: "{id}"},
+	{Pattern: regexp.MustCompile(`^[0-9a-fA-F]{16,}$`), Replacement: "{hash}"},
+}
+
+// NewPathNormalizer creates a normalizer with the given rules.
+// If no rules are given, DefaultPathRules are used.
+func NewPathNormalizer(rules ...PathRule) *PathNormalizer {
+	if len(rules) == 0 {
+		rules = DefaultPathRules
+	}
+	return &PathNormalizer{rules: rules}
+}
+
+// Normalize rewrites each segment of path with the first matching rule.
+func (n *PathNormalizer) Normalize(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		for _, rule := range n.rules {
+			if rule.Pattern.MatchString(segment) {
+				segments[i] = rule.Replacement
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// route returns the route template for the request, if enabled and known.
+func (opts *MiddlewareOptions) route(r *http.Request) string {
+	if !opts.UseRoutePattern {
+		return ""
+	}
+	if opts.GetRoutePattern != nil {
+		if route := opts.GetRoutePattern(r); route != "" {
+			return route
+		}
+	}
+	return RoutePattern(r)
+}
+
+// reportedPath returns the path to report: the route template if enabled
+// and known, otherwise the normalized request path.
+func (opts *MiddlewareOptions) reportedPath(route, path string) string {
+	if opts.UseRoutePattern && route != "" {
+		return route
+	}
+	if opts.PathNormalizer != nil {
+		return opts.PathNormalizer.Normalize(path)
+	}
+	return path
+}
+
+// resolvePath returns the path to report for the request.
+func (opts *MiddlewareOptions) resolvePath(r *http.Request) string {
+	return opts.reportedPath(opts.route(r), r.URL.Path)
+}
@@ -0,0 +1,66 @@
+package entrolytics
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+)
+
+// SamplingRule keeps a fraction of the requests it matches. Empty match
+// fields match everything.
+//
+// Sampling is deterministic per user: the decision is a hash of the UserID,
+// or the SessionID if there is none, so a sampled-in user's whole journey
+// is kept and a user sampled in at a lower rate is also sampled in at any
+// higher rate. Requests with neither are sampled at random.
+type SamplingRule struct {
+	// WebsiteID matches requests for this website.
+	WebsiteID string
+
+	// EventName matches custom events by name, page views as "$pageview",
+	// Web Vitals by metric (e.g. "LCP") and form events by type (e.g. "field_focus").
+	EventName string
+
+	// Endpoint matches requests sent to this API endpoint, e.g. "/api/collect/vitals".
+	Endpoint string
+
+	// Rate is the fraction of matching requests sent, between 0 and 1.
+	// Zero drops every matching request.
+	Rate float64
+}
+
+func (r *SamplingRule) matches(websiteID, name, endpoint string) bool {
+	return (r.WebsiteID == "" || r.WebsiteID == websiteID) &&
+		(r.EventName == "" || r.EventName == name) &&
+		(r.Endpoint == "" || r.Endpoint == endpoint)
+}
+
+// sample applies the first matching sampling rule. It reports whether the
+// request is kept and the rate to record in its payload, which is zero if
+// no rule sampled it.
+func (c *Client) sample(websiteID, name, endpoint, userID, sessionID string) (rate float64, keep bool) {
+	for i := range c.samplingRules {
+		rule := &c.samplingRules[i]
+		if !rule.matches(websiteID, name, endpoint) {
+			continue
+		}
+		if rule.Rate >= 1 {
+			return 0, true
+		}
+		return rule.Rate, sampleValue(userID, sessionID) < rule.Rate
+	}
+	return 0, true
+}
+
+// sampleValue maps the user or session to a stable value in [0, 1).
+func sampleValue(userID, sessionID string) float64 {
+	key := userID
+	if key == "" {
+		key = sessionID
+	}
+	if key == "" {
+		return rand.Float64()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
@@ -0,0 +1,386 @@
+package entrolytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaMode is how events violating a TrackingPlan are handled.
+type SchemaMode int
+
+const (
+	// SchemaReject returns a *SchemaValidationError without sending.
+	SchemaReject SchemaMode = iota
+
+	// SchemaWarn sends the event unchanged and reports the violations to
+	// ClientOptions.OnSchemaViolation.
+	SchemaWarn
+
+	// SchemaStrip removes undeclared properties and sends the event.
+	// Other violations are rejected as in SchemaReject.
+	SchemaStrip
+)
+
+// TrackingPlan declares the allowed events and the shape of their Data, in
+// a subset of JSON Schema. Load one with LoadTrackingPlan or ParseTrackingPlan.
+//
+// Example file:
+//
+//	{
+//	  "events": {
+//	    "purchase": {
+//	      "properties": {
+//	        "revenue":  {"type": "number"},
+//	        "currency": {"type": "string", "enum": ["USD", "EUR"]}
+//	      },
+//	      "required": ["revenue"]
+//	    }
+//	  }
+//	}
+type TrackingPlan struct {
+	// Events maps event names to the schema of their Data.
+	Events map[string]*PropertySchema `json:"events" yaml:"events"`
+}
+
+// PropertySchema describes a value. An event's Data, and objects that
+// declare Properties, may only contain declared properties unless
+// AdditionalProperties is true.
+type PropertySchema struct {
+	// Type is one of "string", "number", "integer", "boolean", "object",
+	// "array" or "null". Empty allows any type.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+
+	// Enum lists the allowed values.
+	Enum []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`
+
+	// Properties declares the properties of an object.
+	Properties map[string]*PropertySchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+
+	// Required lists the properties an object must have.
+	Required []string `json:"required,omitempty" yaml:"required,omitempty"`
+
+	// AdditionalProperties allows undeclared properties in an object.
+	AdditionalProperties bool `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+
+	// Items is the schema of array elements.
+	Items *PropertySchema `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// SchemaViolation is a single way in which an event violates a tracking plan.
+type SchemaViolation struct {
+	// Path locates the offending value, e.g. "data.items[0].sku". It is
+	// empty for violations of the event itself.
+	Path string
+
+	// Message describes the violation.
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// SchemaValidationError is returned when an event violates the tracking plan.
+type SchemaValidationError struct {
+	// Event is the event name.
+	Event string
+
+	// Violations lists every violation found.
+	Violations []SchemaViolation
+}
+
+func (e *SchemaValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("entrolytics: event %q violates tracking plan: %s", e.Event, strings.Join(msgs, "; "))
+}
+
+// LoadTrackingPlan reads a tracking plan from a JSON or YAML file. Files
+// ending in .json are parsed as JSON, others as YAML.
+func LoadTrackingPlan(path string) (*TrackingPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseTrackingPlan(data)
+	}
+
+	var plan TrackingPlan
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("entrolytics: invalid tracking plan %s: %w", path, err)
+	}
+	return &plan, plan.check()
+}
+
+// ParseTrackingPlan parses a tracking plan from JSON.
+func ParseTrackingPlan(data []byte) (*TrackingPlan, error) {
+	var plan TrackingPlan
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("entrolytics: invalid tracking plan: %w", err)
+	}
+	return &plan, plan.check()
+}
+
+// check reports schemas with an unknown type.
+func (p *TrackingPlan) check() error {
+	for name, schema := range p.Events {
+		if err := schema.check("events." + name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PropertySchema) check(path string) error {
+	if s == nil {
+		return nil
+	}
+	switch s.Type {
+	case "", "string", "number", "integer", "boolean", "object", "array", "null":
+	default:
+		return fmt.Errorf("entrolytics: invalid tracking plan: %s: unknown type %q", path, s.Type)
+	}
+	for name, prop := range s.Properties {
+		if err := prop.check(path + ".properties." + name); err != nil {
+			return err
+		}
+	}
+	return s.Items.check(path + ".items")
+}
+
+// Validate checks an event name and its Data against the plan. It returns
+// a *SchemaValidationError listing every violation.
+func (p *TrackingPlan) Validate(name string, data map[string]interface{}) error {
+	if _, err := p.validate(name, data, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validate checks an event against the plan. If strip is set, undeclared
+// properties are removed from the returned data instead of being reported;
+// data itself is never modified.
+func (p *TrackingPlan) validate(name string, data map[string]interface{}, strip bool) (map[string]interface{}, *SchemaValidationError) {
+	schema, ok := p.Events[name]
+	if !ok {
+		if strings.HasPrefix(name, "$") {
+			return data, nil
+		}
+		return nil, &SchemaValidationError{
+			Event:      name,
+			Violations: []SchemaViolation{{Message: "event is not in the tracking plan"}},
+		}
+	}
+	if schema == nil {
+		return data, nil
+	}
+
+	v := &schemaValidator{strip: strip}
+	cleaned := v.validateObject(schema, "data", reflect.ValueOf(data), true)
+	if len(v.violations) > 0 {
+		return nil, &SchemaValidationError{Event: name, Violations: v.violations}
+	}
+	if !strip {
+		return data, nil
+	}
+	return cleaned.(map[string]interface{}), nil
+}
+
+// applySchema validates an event against the client's tracking plan and
+// returns the Data to send.
+func (c *Client) applySchema(event Event) (map[string]interface{}, error) {
+	if c.trackingPlan == nil {
+		return event.Data, nil
+	}
+	data, err := c.trackingPlan.validate(event.Name, event.Data, c.schemaMode == SchemaStrip)
+	if err == nil {
+		return data, nil
+	}
+	if c.schemaMode == SchemaWarn {
+		if c.onSchemaViolation != nil {
+			c.onSchemaViolation(err)
+		}
+		return event.Data, nil
+	}
+	return nil, err
+}
+
+type schemaValidator struct {
+	strip      bool
+	violations []SchemaViolation
+}
+
+func (v *schemaValidator) report(path, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate checks value against s and returns it, with undeclared
+// properties removed if stripping.
+func (v *schemaValidator) validate(s *PropertySchema, path string, value interface{}) interface{} {
+	if s == nil {
+		return value
+	}
+	rv := reflect.ValueOf(value)
+
+	if s.Type != "" && !matchesType(s.Type, rv) {
+		v.report(path, "must be %s, got %s", withArticle(s.Type), describeValue(rv))
+		return value
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, rv) {
+		v.report(path, "must be one of %v", s.Enum)
+	}
+
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		return v.validateObject(s, path, rv, false)
+	case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && s.Items != nil:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = v.validate(s.Items, fmt.Sprintf("%s[%d]", path, i), rv.Index(i).Interface())
+		}
+		if v.strip {
+			return items
+		}
+	}
+	return value
+}
+
+// validateObject checks the properties of a map. Undeclared properties are
+// violations if the schema declares properties or the map is an event's Data.
+func (v *schemaValidator) validateObject(s *PropertySchema, path string, rv reflect.Value, data bool) interface{} {
+	closed := (s.Properties != nil || data) && !s.AdditionalProperties
+
+	for _, name := range s.Required {
+		if !rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())).IsValid() {
+			v.report(path+"."+name, "is required")
+		}
+	}
+
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	obj := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		name := key.String()
+		prop, declared := s.Properties[name]
+		if !declared && closed {
+			if !v.strip {
+				v.report(path+"."+name, "is not in the tracking plan")
+			}
+			continue
+		}
+		obj[name] = v.validate(prop, path+"."+name, rv.MapIndex(key).Interface())
+	}
+	if v.strip {
+		return obj
+	}
+	return rv.Interface()
+}
+
+// matchesType reports whether rv is of the JSON Schema type.
+func matchesType(typ string, rv reflect.Value) bool {
+	if !rv.IsValid() {
+		return typ == "null"
+	}
+	switch typ {
+	case "string":
+		return rv.Kind() == reflect.String && rv.Type() != jsonNumberType
+	case "number":
+		_, ok := numberValue(rv)
+		return ok
+	case "integer":
+		f, ok := numberValue(rv)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	case "boolean":
+		return rv.Kind() == reflect.Bool
+	case "object":
+		return rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String
+	case "array":
+		return rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+	case "null":
+		return (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface ||
+			rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice) && rv.IsNil()
+	}
+	return false
+}
+
+// numberValue returns rv as a float64 if it is numeric. json.Number values
+// are numeric even though their kind is String.
+func numberValue(rv reflect.Value) (float64, bool) {
+	if rv.IsValid() && rv.Type() == jsonNumberType {
+		f, err := rv.Interface().(json.Number).Float64()
+		return f, err == nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// inEnum reports whether rv equals one of the values, comparing numbers by value.
+func inEnum(enum []interface{}, rv reflect.Value) bool {
+	n, isNumber := numberValue(rv)
+	for _, allowed := range enum {
+		av := reflect.ValueOf(allowed)
+		switch {
+		case !rv.IsValid() || !av.IsValid():
+			if rv.IsValid() == av.IsValid() {
+				return true
+			}
+		case isNumber:
+			if m, ok := numberValue(av); ok && m == n {
+				return true
+			}
+		case rv.Kind() == reflect.String && av.Kind() == reflect.String:
+			if rv.String() == av.String() {
+				return true
+			}
+		case rv.Kind() == reflect.Bool && av.Kind() == reflect.Bool:
+			if rv.Bool() == av.Bool() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func describeValue(rv reflect.Value) string {
+	if !rv.IsValid() {
+		return "null"
+	}
+	return rv.Type().String()
+}
+
+func withArticle(typ string) string {
+	switch typ {
+	case "integer", "object", "array":
+		return "an " + typ
+	case "null":
+		return typ
+	}
+	return "a " + typ
+}
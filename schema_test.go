@@ -0,0 +1,215 @@
+package entrolytics
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlan = `{
+	"events": {
+		"purchase": {
+			"properties": {
+				"revenue":  {"type": "number"},
+				"currency": {"type": "string", "enum": ["USD", "EUR"]},
+				"quantity": {"type": "integer"},
+				"gift":     {"type": "boolean"},
+				"coupon":   {"type": "null"},
+				"items": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {"sku": {"type": "string"}},
+						"required": ["sku"]
+					}
+				},
+				"meta": {"type": "object", "additionalProperties": true}
+			},
+			"required": ["revenue"]
+		},
+		"open": {"additionalProperties": true}
+	}
+}`
+
+func TestTrackingPlanValidate(t *testing.T) {
+	plan, err := ParseTrackingPlan([]byte(testPlan))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		event      string
+		data       map[string]interface{}
+		violations []SchemaViolation
+	}{
+		{
+			name:  "valid",
+			event: "purchase",
+			data: map[string]interface{}{
+				"revenue":  9.99,
+				"currency": "EUR",
+				"quantity": 2,
+				"gift":     false,
+				"coupon":   nil,
+				"items":    []interface{}{map[string]interface{}{"sku": "a"}},
+				"meta":     map[string]interface{}{"any": "thing"},
+			},
+		},
+		{
+			name:       "missing required",
+			event:      "purchase",
+			data:       map[string]interface{}{},
+			violations: []SchemaViolation{{Path: "data.revenue", Message: "is required"}},
+		},
+		{
+			name:  "wrong types",
+			event: "purchase",
+			data: map[string]interface{}{
+				"revenue":  "9.99",
+				"quantity": 1.5,
+				"gift":     "no",
+				"coupon":   "SAVE",
+			},
+			violations: []SchemaViolation{
+				{Path: "data.coupon", Message: "must be null, got string"},
+				{Path: "data.gift", Message: "must be a boolean, got string"},
+				{Path: "data.quantity", Message: "must be an integer, got float64"},
+				{Path: "data.revenue", Message: "must be a number, got string"},
+			},
+		},
+		{
+			name:  "integer as float",
+			event: "purchase",
+			data:  map[string]interface{}{"revenue": 1, "quantity": 2.0},
+		},
+		{
+			name:       "enum",
+			event:      "purchase",
+			data:       map[string]interface{}{"revenue": 1, "currency": "GBP"},
+			violations: []SchemaViolation{{Path: "data.currency", Message: "must be one of [USD EUR]"}},
+		},
+		{
+			name:  "array items",
+			event: "purchase",
+			data: map[string]interface{}{
+				"revenue": 1,
+				"items":   []map[string]interface{}{{"sku": "a"}, {"sku": 1, "size": "L"}},
+			},
+			violations: []SchemaViolation{
+				{Path: "data.items[1].size", Message: "is not in the tracking plan"},
+				{Path: "data.items[1].sku", Message: "must be a string, got int"},
+			},
+		},
+		{
+			name:       "undeclared property",
+			event:      "purchase",
+			data:       map[string]interface{}{"revenue": 1, "extra": true},
+			violations: []SchemaViolation{{Path: "data.extra", Message: "is not in the tracking plan"}},
+		},
+		{
+			name:  "additional properties allowed",
+			event: "open",
+			data:  map[string]interface{}{"anything": 1},
+		},
+		{
+			name:       "unknown event",
+			event:      "refund",
+			violations: []SchemaViolation{{Message: "event is not in the tracking plan"}},
+		},
+		{
+			name:  "reserved event",
+			event: "$pageview",
+			data:  map[string]interface{}{"anything": 1},
+		},
+		{
+			name:  "json.Number",
+			event: "purchase",
+			data:  map[string]interface{}{"revenue": json.Number("1.5"), "quantity": json.Number("3")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := plan.Validate(tt.event, tt.data)
+			if tt.violations == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var schemaErr *SchemaValidationError
+			require.True(t, errors.As(err, &schemaErr), "got %v", err)
+			assert.Equal(t, tt.event, schemaErr.Event)
+			assert.Equal(t, tt.violations, schemaErr.Violations)
+		})
+	}
+}
+
+func TestTrackSchemaModes(t *testing.T) {
+	plan, err := ParseTrackingPlan([]byte(testPlan))
+	require.NoError(t, err)
+	data := map[string]interface{}{
+		"revenue": 1,
+		"extra":   true,
+		"items":   []interface{}{map[string]interface{}{"sku": "a", "size": "L"}},
+	}
+
+	tests := []struct {
+		name     string
+		mode     SchemaMode
+		wantErr  bool
+		wantWarn bool
+		wantData map[string]interface{}
+	}{
+		{name: "reject", mode: SchemaReject, wantErr: true},
+		{
+			name:     "warn",
+			mode:     SchemaWarn,
+			wantWarn: true,
+			wantData: map[string]interface{}{
+				"revenue": float64(1),
+				"extra":   true,
+				"items":   []interface{}{map[string]interface{}{"sku": "a", "size": "L"}},
+			},
+		},
+		{
+			name: "strip",
+			mode: SchemaStrip,
+			wantData: map[string]interface{}{
+				"revenue": float64(1),
+				"items":   []interface{}{map[string]interface{}{"sku": "a"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warned *SchemaValidationError
+			client, bodies := newTestClient(t, ClientOptions{
+				TrackingPlan:      plan,
+				SchemaMode:        tt.mode,
+				OnSchemaViolation: func(err *SchemaValidationError) { warned = err },
+			})
+
+			err := client.Track(Event{WebsiteID: "site", Name: "purchase", Data: data})
+			assert.Equal(t, tt.wantWarn, warned != nil)
+			if tt.wantErr {
+				var schemaErr *SchemaValidationError
+				assert.True(t, errors.As(err, &schemaErr))
+				assert.Empty(t, *bodies)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, *bodies, 1)
+
+			var sent struct {
+				Payload struct {
+					Data map[string]interface{} `json:"data"`
+				} `json:"payload"`
+			}
+			require.NoError(t, json.Unmarshal((*bodies)[0], &sent))
+			assert.Equal(t, tt.wantData, sent.Payload.Data)
+			assert.Contains(t, data, "extra", "data must not be modified")
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package entrolytics
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultSketchRelativeAccuracy is the default relative accuracy of DDSketch quantiles.
+const DefaultSketchRelativeAccuracy = 0.01
+
+// minSketchValue is the smallest value tracked in a log bucket; smaller
+// values, such as a CLS of 0, are counted in the zero bucket.
+const minSketchValue = 1e-9
+
+// DDSketch is a mergeable quantile sketch with relative-error guarantees
+// (https://arxiv.org/abs/1908.10693). Any quantile it returns is within the
+// configured relative accuracy of the true value. It accepts non-negative
+// values only and is not safe for concurrent use.
+type DDSketch struct {
+	relativeAccuracy float64
+	gamma            float64
+	logGamma         float64
+
+	buckets   map[int]uint64
+	zeroCount uint64
+	count     uint64
+	min       float64
+	max       float64
+	sum       float64
+}
+
+// NewDDSketch creates a sketch with the given relative accuracy, between 0
+// and 1. Values outside that range use DefaultSketchRelativeAccuracy.
+func NewDDSketch(relativeAccuracy float64) *DDSketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = DefaultSketchRelativeAccuracy
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &DDSketch{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		buckets:          make(map[int]uint64),
+	}
+}
+
+// Add records a value. Negative and NaN values are ignored.
+func (s *DDSketch) Add(value float64) {
+	if value < 0 || math.IsNaN(value) {
+		return
+	}
+
+	if value < minSketchValue {
+		s.zeroCount++
+	} else {
+		s.buckets[int(math.Ceil(math.Log(value)/s.logGamma))]++
+	}
+
+	if s.count == 0 || value < s.min {
+		s.min = value
+	}
+	if s.count == 0 || value > s.max {
+		s.max = value
+	}
+	s.count++
+	s.sum += value
+}
+
+// Merge adds the values recorded by other, which must have the same relative accuracy.
+func (s *DDSketch) Merge(other *DDSketch) {
+	if other.count == 0 {
+		return
+	}
+	for index, n := range other.buckets {
+		s.buckets[index] += n
+	}
+	s.zeroCount += other.zeroCount
+	if s.count == 0 || other.min < s.min {
+		s.min = other.min
+	}
+	if s.count == 0 || other.max > s.max {
+		s.max = other.max
+	}
+	s.count += other.count
+	s.sum += other.sum
+}
+
+// Quantile returns the approximate value at quantile q, between 0 and 1.
+// It returns 0 if the sketch is empty.
+func (s *DDSketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	rank := uint64(q * float64(s.count-1))
+	if rank < s.zeroCount {
+		return 0
+	}
+
+	indexes := make([]int, 0, len(s.buckets))
+	for index := range s.buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	seen := s.zeroCount
+	for _, index := range indexes {
+		seen += s.buckets[index]
+		if seen > rank {
+			// Midpoint of the bucket, clamped to the observed range
+			value := 2 * math.Pow(s.gamma, float64(index)) / (s.gamma + 1)
+			return math.Min(math.Max(value, s.min), s.max)
+		}
+	}
+	return s.max
+}
+
+// Count returns the number of values recorded.
+func (s *DDSketch) Count() uint64 {
+	return s.count
+}
+
+// Min returns the smallest value recorded.
+func (s *DDSketch) Min() float64 {
+	return s.min
+}
+
+// Max returns the largest value recorded.
+func (s *DDSketch) Max() float64 {
+	return s.max
+}
+
+// Sum returns the sum of the values recorded.
+func (s *DDSketch) Sum() float64 {
+	return s.sum
+}
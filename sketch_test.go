@@ -0,0 +1,91 @@
+package entrolytics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDDSketchQuantiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	generate := func(n int, f func() float64) []float64 {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = f()
+		}
+		return values
+	}
+
+	tests := []struct {
+		name     string
+		accuracy float64
+		values   []float64
+	}{
+		{name: "uniform", accuracy: 0.01, values: generate(10000, func() float64 { return rng.Float64() * 5000 })},
+		{name: "exponential", accuracy: 0.01, values: generate(10000, func() float64 { return rng.ExpFloat64() * 2500 })},
+		{name: "lognormal", accuracy: 0.02, values: generate(10000, func() float64 { return math.Exp(rng.NormFloat64()*2 + 5) })},
+		{name: "small values", accuracy: 0.01, values: generate(1000, func() float64 { return rng.Float64() * 0.25 })},
+		{name: "with zeros", accuracy: 0.01, values: append(make([]float64, 300), generate(700, rng.Float64)...)},
+		{name: "single value", accuracy: 0.05, values: []float64{1234}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewDDSketch(tt.accuracy)
+			for _, v := range tt.values {
+				s.Add(v)
+			}
+			sorted := append([]float64(nil), tt.values...)
+			sort.Float64s(sorted)
+
+			assert.Equal(t, uint64(len(sorted)), s.Count())
+			assert.Equal(t, sorted[0], s.Min())
+			assert.Equal(t, sorted[len(sorted)-1], s.Max())
+
+			for _, q := range []float64{0, 0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99, 1} {
+				want := sorted[int(q*float64(len(sorted)-1))]
+				got := s.Quantile(q)
+				assert.InDelta(t, want, got, want*tt.accuracy+1e-12, "q=%v", q)
+			}
+		})
+	}
+}
+
+func TestDDSketchMerge(t *testing.T) {
+	whole, a, b := NewDDSketch(0.01), NewDDSketch(0.01), NewDDSketch(0.01)
+	for i := 0; i < 1000; i++ {
+		v := float64(i)
+		whole.Add(v)
+		if i%3 == 0 {
+			a.Add(v)
+		} else {
+			b.Add(v)
+		}
+	}
+	a.Merge(b)
+	a.Merge(NewDDSketch(0.01))
+
+	assert.Equal(t, whole.Count(), a.Count())
+	assert.Equal(t, whole.Min(), a.Min())
+	assert.Equal(t, whole.Max(), a.Max())
+	assert.Equal(t, whole.Sum(), a.Sum())
+	for _, q := range []float64{0.5, 0.75, 0.95} {
+		assert.Equal(t, whole.Quantile(q), a.Quantile(q), "q=%v", q)
+	}
+}
+
+func TestDDSketchIgnoresInvalidValues(t *testing.T) {
+	s := NewDDSketch(0)
+	assert.Equal(t, 0.0, s.Quantile(0.5))
+
+	s.Add(-1)
+	s.Add(math.NaN())
+	assert.Equal(t, uint64(0), s.Count())
+
+	s.Add(2)
+	assert.Equal(t, uint64(1), s.Count())
+	assert.Equal(t, 2.0, s.Quantile(0.5))
+}
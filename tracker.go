@@ -0,0 +1,191 @@
+package entrolytics
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracker tracks events on behalf of a single request. Website, URL,
+// referrer, user agent, IP address, user, session and campaign are filled
+// in on every event, so handlers only provide the event name and data.
+//
+// TrackerMiddleware stores a Tracker in each request context; retrieve it
+// anywhere down the call stack with FromContext:
+//
+//	func createAccount(ctx context.Context, plan string) error {
+//	    // ...
+//	    return entrolytics.FromContext(ctx).Track("signup", map[string]interface{}{"plan": plan})
+//	}
+//
+// Methods on a nil Tracker return ErrNoTracker.
+type Tracker struct {
+	client *Client
+
+	// WebsiteID is your Entrolytics website ID.
+	WebsiteID string
+
+	// URL is the request path.
+	URL string
+
+	// Referrer is the referrer URL.
+	Referrer string
+
+	// UserAgent is the client's user agent string.
+	UserAgent string
+
+	// IPAddress is the client's IP address.
+	IPAddress string
+
+	// UserID identifies a logged-in user.
+	UserID string
+
+	// SessionID identifies the user session.
+	SessionID string
+
+	// Campaign is the marketing attribution for the request.
+	Campaign Campaign
+}
+
+// NewTracker creates a tracker pre-populated from the request. The request
+// may be nil for frameworks that do not use http.Request, in which case the
+// caller fills in the fields.
+func NewTracker(client *Client, websiteID string, r *http.Request) *Tracker {
+	t := &Tracker{client: client, WebsiteID: websiteID}
+	if r != nil {
+		t.URL = r.URL.Path
+		t.Referrer = r.Referer()
+		t.UserAgent = r.UserAgent()
+		t.IPAddress = getClientIP(r)
+	}
+	return t
+}
+
+type trackerContextKey struct{}
+
+// TrackerMiddleware creates HTTP middleware that stores a request-scoped
+// Tracker in the request context. User and session IDs come from
+// opts.GetUserID and opts.GetSessionID, and campaign attribution from
+// opts.ResolveCampaign; other options are ignored.
+//
+// Example:
+//
+//	handler := entrolytics.TrackerMiddleware(client, "website_id", entrolytics.MiddlewareOptions{})(mux)
+func TrackerMiddleware(client *Client, websiteID string, opts MiddlewareOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := NewTracker(client, websiteID, r)
+			if opts.GetUserID != nil {
+				t.UserID = opts.GetUserID(r)
+			}
+			if opts.GetSessionID != nil {
+				t.SessionID = opts.GetSessionID(r)
+			}
+			t.Campaign = opts.ResolveCampaign(w, r)
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), t)))
+		})
+	}
+}
+
+// NewContext returns a copy of ctx carrying the tracker.
+func NewContext(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerContextKey{}, t)
+}
+
+// FromContext returns the tracker stored in ctx, or nil if there is none.
+// Calling methods on the nil tracker is safe and returns ErrNoTracker.
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerContextKey{}).(*Tracker)
+	return t
+}
+
+// Client returns the underlying client, or nil for a nil tracker.
+func (t *Tracker) Client() *Client {
+	if t == nil {
+		return nil
+	}
+	return t.client
+}
+
+// Track sends a custom event with the request fields filled in.
+func (t *Tracker) Track(name string, data map[string]interface{}) error {
+	return t.TrackWithContext(context.Background(), name, data)
+}
+
+// TrackWithContext sends a custom event with context for cancellation.
+func (t *Tracker) TrackWithContext(ctx context.Context, name string, data map[string]interface{}) error {
+	if t == nil {
+		return ErrNoTracker
+	}
+	event := t.event(name)
+	event.Data = data
+	return t.client.TrackWithContext(ctx, event)
+}
+
+// TrackProperties sends a custom event with typed properties and the
+// request fields filled in.
+func (t *Tracker) TrackProperties(name string, props *Properties) error {
+	return t.TrackPropertiesWithContext(context.Background(), name, props)
+}
+
+// TrackPropertiesWithContext sends a custom event with typed properties and
+// context for cancellation.
+func (t *Tracker) TrackPropertiesWithContext(ctx context.Context, name string, props *Properties) error {
+	if t == nil {
+		return ErrNoTracker
+	}
+	event := t.event(name)
+	event.Properties = props
+	return t.client.TrackWithContext(ctx, event)
+}
+
+// event returns an event with the request fields filled in.
+func (t *Tracker) event(name string) Event {
+	return Event{
+		WebsiteID: t.WebsiteID,
+		Name:      name,
+		URL:       t.URL,
+		Referrer:  t.Referrer,
+		UserID:    t.UserID,
+		SessionID: t.SessionID,
+		UserAgent: t.UserAgent,
+		IPAddress: t.IPAddress,
+		Campaign:  t.Campaign,
+	}
+}
+
+// Identify sends user identification for the tracker's user.
+func (t *Tracker) Identify(traits map[string]interface{}) error {
+	return t.IdentifyWithContext(context.Background(), traits)
+}
+
+// IdentifyWithContext sends user identification with context for cancellation.
+func (t *Tracker) IdentifyWithContext(ctx context.Context, traits map[string]interface{}) error {
+	if t == nil {
+		return ErrNoTracker
+	}
+	return t.client.IdentifyWithContext(ctx, Identify{
+		WebsiteID: t.WebsiteID,
+		UserID:    t.UserID,
+		Traits:    traits,
+	})
+}
+
+// IdentifyProperties sends user identification with typed traits for the
+// tracker's user.
+func (t *Tracker) IdentifyProperties(traits *Properties) error {
+	return t.IdentifyPropertiesWithContext(context.Background(), traits)
+}
+
+// IdentifyPropertiesWithContext sends user identification with typed traits
+// and context for cancellation.
+func (t *Tracker) IdentifyPropertiesWithContext(ctx context.Context, traits *Properties) error {
+	if t == nil {
+		return ErrNoTracker
+	}
+	return t.client.IdentifyWithContext(ctx, Identify{
+		WebsiteID:       t.WebsiteID,
+		UserID:          t.UserID,
+		TraitProperties: traits,
+	})
+}
@@ -0,0 +1,184 @@
+package entrolytics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OutboundEventName is the default event name for tracked outbound requests.
+const OutboundEventName = "$outbound_request"
+
+// Transport is an http.RoundTripper that tracks outbound requests, such as
+// calls to partner APIs, as events with the host, method, route, status and
+// duration. Failed requests report the class of error, such as "timeout" or
+// "dns", rather than its message, which may contain the request URL and its
+// credentials. Events are sent in the background and never delay the request.
+//
+// Example:
+//
+//	httpClient := &http.Client{
+//	    Transport: entrolytics.NewTransport(client, "website_id"),
+//	}
+type Transport struct {
+	// Base is the underlying RoundTripper. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Client is the Entrolytics client used for tracking (required).
+	Client *Client
+
+	// WebsiteID is your Entrolytics website ID (required).
+	WebsiteID string
+
+	// EventName is the event name for tracked requests. Defaults to OutboundEventName.
+	EventName string
+
+	// SampleRate is the fraction of requests tracked, between 0 and 1.
+	// Zero tracks every request.
+	SampleRate float64
+
+	// AllowHosts, if set, restricts tracking to these hosts. A leading "."
+	// or "*." also matches subdomains, e.g. ".stripe.com".
+	AllowHosts []string
+
+	// DenyHosts are hosts that are never tracked, in the same format as
+	// AllowHosts. Takes precedence over AllowHosts. Requests to the
+	// Entrolytics API host are never tracked.
+	DenyHosts []string
+
+	// GetRoutePattern returns the route template for the request, e.g.
+	// "/v1/customers/{id}". Requests without one report the path normalized
+	// by PathNormalizer.
+	GetRoutePattern func(r *http.Request) string
+
+	// PathNormalizer rewrites paths without a route template.
+	// Defaults to NewPathNormalizer() with DefaultPathRules.
+	PathNormalizer *PathNormalizer
+
+	// OnError is called when an error occurs during asynchronous tracking.
+	OnError func(err error)
+}
+
+var defaultPathNormalizer = NewPathNormalizer()
+
+// NewTransport creates a tracking transport wrapping http.DefaultTransport.
+func NewTransport(client *Client, websiteID string) *Transport {
+	return &Transport{Client: client, WebsiteID: websiteID}
+}
+
+// RoundTrip performs the request with the base transport and tracks it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !t.shouldTrack(req) {
+		return base.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	duration := time.Since(start)
+
+	route := t.route(req)
+	data := map[string]interface{}{
+		"host":        req.URL.Hostname(),
+		"method":      req.Method,
+		"route":       route,
+		"duration_ms": duration.Milliseconds(),
+	}
+	if err != nil {
+		data["error"] = outboundErrorClass(err)
+	} else {
+		data["status"] = resp.StatusCode
+	}
+
+	eventName := t.EventName
+	if eventName == "" {
+		eventName = OutboundEventName
+	}
+
+	// The request context may be canceled once the response is read
+	t.Client.trackAsync(context.WithoutCancel(req.Context()), Event{
+		WebsiteID: t.WebsiteID,
+		Name:      eventName,
+		Data:      data,
+		URL:       req.URL.Scheme + "://" + req.URL.Host + route,
+	}, t.OnError)
+
+	return resp, err
+}
+
+func (t *Transport) shouldTrack(req *http.Request) bool {
+	host := strings.ToLower(req.URL.Hostname())
+
+	// Never track the SDK's own requests
+	if strings.EqualFold(t.Client.hostname, host) {
+		return false
+	}
+	if matchHost(t.DenyHosts, host) {
+		return false
+	}
+	if len(t.AllowHosts) > 0 && !matchHost(t.AllowHosts, host) {
+		return false
+	}
+	return t.SampleRate <= 0 || t.SampleRate >= 1 || rand.Float64() < t.SampleRate
+}
+
+func (t *Transport) route(req *http.Request) string {
+	if t.GetRoutePattern != nil {
+		if route := t.GetRoutePattern(req); route != "" {
+			return route
+		}
+	}
+	normalizer := t.PathNormalizer
+	if normalizer == nil {
+		normalizer = defaultPathNormalizer
+	}
+	return normalizer.Normalize(req.URL.Path)
+}
+
+// outboundErrorClass describes a transport error without its message.
+func outboundErrorClass(err error) string {
+	var (
+		dnsErr    *net.DNSError
+		netErr    net.Error
+		certErr   *tls.CertificateVerificationError
+		unknownCA x509.UnknownAuthorityError
+		opErr     *net.OpError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &certErr), errors.As(err, &unknownCA):
+		return "tls"
+	case errors.As(err, &opErr):
+		return opErr.Op
+	}
+	return "error"
+}
+
+// matchHost reports whether host matches any of the patterns.
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimPrefix(pattern, "*"))
+		if strings.HasPrefix(pattern, ".") {
+			if host == pattern[1:] || strings.HasSuffix(host, pattern) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
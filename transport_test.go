@@ -0,0 +1,52 @@
+package entrolytics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboundErrorClass(t *testing.T) {
+	secretURL := "https://api.partner.example/v1/charges?api_key=sk_live_secret"
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"dial", &url.Error{Op: "Get", URL: secretURL, Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}, "dial"},
+		{"dns", &url.Error{Op: "Get", URL: secretURL, Err: &net.DNSError{Err: "no such host", Name: "api.partner.example", IsNotFound: true}}, "dns"},
+		{"deadline", &url.Error{Op: "Get", URL: secretURL, Err: context.DeadlineExceeded}, "timeout"},
+		{"canceled", context.Canceled, "canceled"},
+		{"other", errors.New(secretURL), "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := outboundErrorClass(tt.err)
+			assert.Equal(t, tt.want, got)
+			assert.NotContains(t, got, "secret")
+		})
+	}
+}
+
+func TestTransportSkipsAPIHost(t *testing.T) {
+	client := NewClientWithOptions(ClientOptions{APIKey: "ent_test", Host: "https://Collect.Example.com:8443"})
+	transport := NewTransport(client, "site")
+
+	assert.False(t, transport.shouldTrack(mustRequest(t, "https://collect.example.com/api/send")))
+	assert.True(t, transport.shouldTrack(mustRequest(t, "https://api.partner.example/v1")))
+}
+
+func mustRequest(t *testing.T, rawURL string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
@@ -13,6 +13,10 @@ type Event struct {
 	// Data contains additional event data.
 	Data map[string]interface{}
 
+	// Properties contains typed event data, sent alongside Data. Properties
+	// take precedence over Data keys with the same name.
+	Properties *Properties
+
 	// URL is the page URL where the event occurred.
 	URL string
 
@@ -31,6 +35,9 @@ type Event struct {
 	// IPAddress is the client's IP address for geo data.
 	IPAddress string
 
+	// Campaign is the marketing attribution for the event.
+	Campaign Campaign
+
 	// Timestamp is when the event occurred. Defaults to now if empty.
 	Timestamp time.Time
 }
@@ -49,6 +56,12 @@ type PageView struct {
 	// Title is the page title.
 	Title string
 
+	// Data contains additional page view data.
+	Data map[string]interface{}
+
+	// Properties contains typed page view data, sent alongside Data.
+	Properties *Properties
+
 	// UserID identifies a logged-in user.
 	UserID string
 
@@ -61,6 +74,9 @@ type PageView struct {
 	// IPAddress is the client's IP address.
 	IPAddress string
 
+	// Campaign is the marketing attribution for the page view.
+	Campaign Campaign
+
 	// Timestamp is when the page view occurred.
 	Timestamp time.Time
 }
@@ -76,6 +92,9 @@ type Identify struct {
 	// Traits are user attributes like email, plan, company.
 	Traits map[string]interface{}
 
+	// TraitProperties contains typed traits, sent alongside Traits.
+	TraitProperties *Properties
+
 	// Timestamp is when the identification occurred.
 	Timestamp time.Time
 }
@@ -95,11 +114,63 @@ type ClientOptions struct {
 	// Host is the Entrolytics API host. Defaults to https://entrolytics.click.
 	Host string
 
+	// Endpoint is the collection endpoint for events and page views, such
+	// as "/api/collect" or "/api/send-native". Defaults to DefaultEndpoint.
+	Endpoint string
+
+	// WebsiteID is the website ID used when an event, page view, or other
+	// request does not set one.
+	WebsiteID string
+
 	// Timeout is the HTTP request timeout. Defaults to 10 seconds.
 	Timeout time.Duration
 
 	// UserAgent is the User-Agent header for requests.
 	UserAgent string
+
+	// Debug logs the endpoint, size and status of every request with the
+	// standard logger. Request bodies are never logged.
+	Debug bool
+
+	// Disabled makes the client validate but not send anything, for
+	// example in local development and tests. An API key is not required.
+	Disabled bool
+
+	// DataLimits bounds the size of Data, Traits and Attribution maps,
+	// which are checked and normalized with NormalizeData before sending.
+	// No limits apply by default; set RecommendedDataLimits to opt in.
+	DataLimits DataLimits
+
+	// SamplingRules sample events, page views, Web Vitals and form events.
+	// The first rule matching a request applies; requests matching no rule
+	// are always sent. Identify calls and deployments are never sampled.
+	SamplingRules []SamplingRule
+
+	// TrackingPlan, if set, validates custom events sent with Track against
+	// the declared event names and Data properties. Events named with a
+	// leading "$", such as those sent by the middleware, are only validated
+	// if the plan declares them.
+	TrackingPlan *TrackingPlan
+
+	// SchemaMode is how events violating the TrackingPlan are handled.
+	// Defaults to SchemaReject.
+	SchemaMode SchemaMode
+
+	// OnSchemaViolation is called with the violations of events sent
+	// anyway in SchemaWarn mode.
+	OnSchemaViolation func(err *SchemaValidationError)
+
+	// RateLimiter limits the rate of requests to each endpoint. Requests
+	// over the limit fail fast with ErrClientRateLimited. Nil disables it.
+	RateLimiter *RateLimiterOptions
+
+	// CircuitBreaker stops requests after consecutive failures. Nil disables it.
+	CircuitBreaker *CircuitBreakerOptions
+
+	// VitalThresholds overrides the thresholds used to rate Web Vitals sent
+	// without a Rating, keyed by website ID. Metrics without an override use
+	// DefaultVitalThresholds.
+	VitalThresholds map[string]map[VitalMetric]VitalThresholds
 }
 
 // eventPayload is the internal structure for sending events.
@@ -109,21 +180,35 @@ type eventPayload struct {
 }
 
 type trackPayload struct {
-	Website   string                 `json:"website"`
-	Name      string                 `json:"name"`
-	Data      map[string]interface{} `json:"data,omitempty"`
-	URL       string                 `json:"url,omitempty"`
-	Referrer  string                 `json:"referrer,omitempty"`
-	UserID    string                 `json:"userId,omitempty"`
-	SessionID string                 `json:"sessionId,omitempty"`
-	Timestamp string                 `json:"timestamp"`
+	Website    string           `json:"website"`
+	Name       string           `json:"name"`
+	Data       interface{}      `json:"data,omitempty"`
+	URL        string           `json:"url,omitempty"`
+	Referrer   string           `json:"referrer,omitempty"`
+	UserID     string           `json:"userId,omitempty"`
+	SessionID  string           `json:"sessionId,omitempty"`
+	Campaign   *campaignPayload `json:"campaign,omitempty"`
+	SampleRate float64          `json:"sampleRate,omitempty"`
+	Timestamp  string           `json:"timestamp"`
+}
+
+type campaignPayload struct {
+	Source  string `json:"source,omitempty"`
+	Medium  string `json:"medium,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Term    string `json:"term,omitempty"`
+	Content string `json:"content,omitempty"`
+	ID      string `json:"id,omitempty"`
+	GCLID   string `json:"gclid,omitempty"`
+	FBCLID  string `json:"fbclid,omitempty"`
+	MSCLKID string `json:"msclkid,omitempty"`
 }
 
 type identifyPayload struct {
-	Website   string                 `json:"website"`
-	UserID    string                 `json:"userId"`
-	Traits    map[string]interface{} `json:"traits,omitempty"`
-	Timestamp string                 `json:"timestamp"`
+	Website   string      `json:"website"`
+	UserID    string      `json:"userId"`
+	Traits    interface{} `json:"traits,omitempty"`
+	Timestamp string      `json:"timestamp"`
 }
 
 // ============================================================================
@@ -146,6 +231,15 @@ const (
 	FCP VitalMetric = "FCP"
 )
 
+// Valid reports whether m is one of the supported metrics.
+func (m VitalMetric) Valid() bool {
+	switch m {
+	case LCP, INP, CLS, TTFB, FCP:
+		return true
+	}
+	return false
+}
+
 // VitalRating represents a Web Vital performance rating.
 type VitalRating string
 
@@ -158,6 +252,15 @@ const (
 	Poor VitalRating = "poor"
 )
 
+// Valid reports whether r is one of the supported ratings.
+func (r VitalRating) Valid() bool {
+	switch r {
+	case Good, NeedsImprovement, Poor:
+		return true
+	}
+	return false
+}
+
 // NavigationType represents how the page was navigated to.
 type NavigationType string
 
@@ -182,7 +285,8 @@ type WebVital struct {
 	// Value is the metric value in milliseconds (except CLS which is unitless) (required).
 	Value float64
 
-	// Rating indicates performance: good, needs-improvement, or poor (required).
+	// Rating indicates performance: good, needs-improvement, or poor.
+	// Derived from Value if empty.
 	Rating VitalRating
 
 	// Delta is the difference from the previous value.
@@ -197,6 +301,9 @@ type WebVital struct {
 	// Attribution provides debug information about the metric.
 	Attribution map[string]interface{}
 
+	// AttributionProperties contains typed attribution, sent alongside Attribution.
+	AttributionProperties *Properties
+
 	// URL is the full page URL.
 	URL string
 
@@ -206,22 +313,45 @@ type WebVital struct {
 	// SessionID identifies the user session.
 	SessionID string
 
+	// UserAgent is the client's user agent string.
+	UserAgent string
+
+	// IPAddress is the client's IP address.
+	IPAddress string
+
 	// Timestamp is when the metric was recorded.
 	Timestamp time.Time
 }
 
 type vitalPayload struct {
-	Website        string                 `json:"website"`
-	Metric         VitalMetric            `json:"metric"`
-	Value          float64                `json:"value"`
-	Rating         VitalRating            `json:"rating"`
-	Delta          float64                `json:"delta,omitempty"`
-	ID             string                 `json:"id,omitempty"`
-	NavigationType NavigationType         `json:"navigationType,omitempty"`
-	Attribution    map[string]interface{} `json:"attribution,omitempty"`
-	URL            string                 `json:"url,omitempty"`
-	Path           string                 `json:"path,omitempty"`
-	SessionID      string                 `json:"sessionId,omitempty"`
+	Website        string         `json:"website"`
+	Metric         VitalMetric    `json:"metric"`
+	Value          float64        `json:"value"`
+	Rating         VitalRating    `json:"rating"`
+	Delta          float64        `json:"delta,omitempty"`
+	ID             string         `json:"id,omitempty"`
+	NavigationType NavigationType `json:"navigationType,omitempty"`
+	Attribution    interface{}    `json:"attribution,omitempty"`
+	URL            string         `json:"url,omitempty"`
+	Path           string         `json:"path,omitempty"`
+	SessionID      string         `json:"sessionId,omitempty"`
+	SampleRate     float64        `json:"sampleRate,omitempty"`
+}
+
+type vitalSummaryPayload struct {
+	Website     string      `json:"website"`
+	Metric      VitalMetric `json:"metric"`
+	Path        string      `json:"path,omitempty"`
+	Count       uint64      `json:"count"`
+	Min         float64     `json:"min"`
+	Max         float64     `json:"max"`
+	Sum         float64     `json:"sum"`
+	P50         float64     `json:"p50"`
+	P75         float64     `json:"p75"`
+	P95         float64     `json:"p95"`
+	Rating      VitalRating `json:"rating"`
+	WindowStart string      `json:"windowStart"`
+	WindowEnd   string      `json:"windowEnd"`
 }
 
 // ============================================================================
@@ -285,6 +415,12 @@ type FormEvent struct {
 	// Success indicates whether the submission was successful.
 	Success bool
 
+	// FieldCount is the number of fields submitted (for submit events).
+	FieldCount int
+
+	// FieldNames are the names of the fields submitted (for submit events).
+	FieldNames []string
+
 	// SessionID identifies the user session.
 	SessionID string
 
@@ -305,7 +441,10 @@ type formEventPayload struct {
 	TimeSinceStart int           `json:"timeSinceStart,omitempty"`
 	ErrorMessage   string        `json:"errorMessage,omitempty"`
 	Success        bool          `json:"success,omitempty"`
+	FieldCount     int           `json:"fieldCount,omitempty"`
+	FieldNames     []string      `json:"fieldNames,omitempty"`
 	SessionID      string        `json:"sessionId,omitempty"`
+	SampleRate     float64       `json:"sampleRate,omitempty"`
 }
 
 // ============================================================================
@@ -0,0 +1,69 @@
+package entrolytics
+
+import "math"
+
+// VitalThresholds are the rating boundaries for a Web Vital metric.
+type VitalThresholds struct {
+	// Good is the largest value rated good.
+	Good float64
+
+	// NeedsImprovement is the largest value rated needs-improvement.
+	// Larger values are rated poor.
+	NeedsImprovement float64
+}
+
+// DefaultVitalThresholds are the thresholds recommended by Google
+// (https://web.dev/articles/vitals), in milliseconds except for CLS.
+var DefaultVitalThresholds = map[VitalMetric]VitalThresholds{
+	LCP:  {Good: 2500, NeedsImprovement: 4000},
+	INP:  {Good: 200, NeedsImprovement: 500},
+	CLS:  {Good: 0.1, NeedsImprovement: 0.25},
+	TTFB: {Good: 800, NeedsImprovement: 1800},
+	FCP:  {Good: 1800, NeedsImprovement: 3000},
+}
+
+// Maximum plausible vital values. Larger values indicate a broken measurement.
+const (
+	// maxVitalDuration is the largest accepted timing metric, in milliseconds.
+	maxVitalDuration = 10 * 60 * 1000
+
+	// maxLayoutShift is the largest accepted CLS score.
+	maxLayoutShift = 100
+)
+
+// Rate returns the rating for value.
+func (t VitalThresholds) Rate(value float64) VitalRating {
+	switch {
+	case value <= t.Good:
+		return Good
+	case value <= t.NeedsImprovement:
+		return NeedsImprovement
+	default:
+		return Poor
+	}
+}
+
+// RateVital returns the rating for a metric value using DefaultVitalThresholds.
+func RateVital(metric VitalMetric, value float64) VitalRating {
+	return DefaultVitalThresholds[metric].Rate(value)
+}
+
+// thresholds returns the thresholds for a metric, preferring the website's overrides.
+func (c *Client) thresholds(websiteID string, metric VitalMetric) VitalThresholds {
+	if t, ok := c.vitalThresholds[websiteID][metric]; ok {
+		return t
+	}
+	return DefaultVitalThresholds[metric]
+}
+
+// validVitalValue reports whether value is plausible for the metric. All
+// metrics are non-negative; timings are milliseconds and CLS is unitless.
+func validVitalValue(metric VitalMetric, value float64) bool {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+		return false
+	}
+	if metric == CLS {
+		return value <= maxLayoutShift
+	}
+	return value <= maxVitalDuration
+}